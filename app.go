@@ -0,0 +1,219 @@
+package phrasegen
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ferux/phraseGen/markov"
+	"github.com/ferux/phraseGen/utils"
+)
+
+// App is an instance of the application. It owns configuration and
+// dependencies which are passed to components explicitly, so several
+// independent instances may work in one process.
+type App struct {
+	Config   Configuration
+	Logger   *logrus.Logger
+	Reporter ErrorReporter
+	Models   *Store
+}
+
+// NewApp creates application by configuration. Logger and error reporter
+// are built according to it.
+func NewApp(c Configuration) (*App, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	reporter, err := NewErrorReporter(c)
+	if err != nil {
+		return nil, err
+	}
+	logger := logrus.New()
+	if lvl, err := logrus.ParseLevel(c.Log.Level); err == nil {
+		logger.Level = lvl
+	}
+	if c.Log.Format == "json" {
+		logger.Formatter = &logrus.JSONFormatter{}
+	}
+	return &App{
+		Config:   c,
+		Logger:   logger,
+		Reporter: reporter,
+		Models:   NewStore(),
+	}, nil
+}
+
+// Log returns logger of the package.
+func (a *App) Log(pkg string) *logrus.Entry {
+	return a.Logger.WithFields(logrus.Fields{
+		"version":  Version,
+		"revision": Revision,
+		"pkg":      pkg,
+	})
+}
+
+// Close releases resources of the application.
+func (a *App) Close() error {
+	return a.Reporter.Close()
+}
+
+// Corpus holds preprocessing of texts set by configuration.
+type Corpus struct {
+	Pipeline *utils.Pipeline
+	// Dedup is nil unless pipeline has dedupe stage.
+	Dedup *utils.Deduplicator
+	// Blocklist is nil unless it's configured.
+	Blocklist *utils.Blocklist
+}
+
+// NewCorpus builds preprocessing pipeline and loads blocklist.
+func (a *App) NewCorpus() (*Corpus, error) {
+	cfg := a.Config.Corpus
+	names := make([]string, 0, len(cfg.Pipeline))
+	for _, name := range cfg.Pipeline {
+		// scrub-pii is kept as a switch of pii stage
+		if name != "pii" || cfg.ScrubPII {
+			names = append(names, name)
+		}
+	}
+	pipeline, err := utils.ParsePipeline(strings.Join(names, ","))
+	if err != nil {
+		return nil, fmt.Errorf("create pipeline: %w", err)
+	}
+	c := &Corpus{Pipeline: pipeline}
+	for _, st := range pipeline.Stages() {
+		if d, ok := st.(*utils.Deduplicator); ok {
+			d.SetThreshold(cfg.DedupeThreshold)
+			c.Dedup = d
+		}
+	}
+	if len(cfg.Blocklist) > 0 {
+		if c.Blocklist, err = utils.LoadBlocklist(cfg.Blocklist...); err != nil {
+			return nil, fmt.Errorf("load blocklist: %w", err)
+		}
+		mode, _ := utils.ParseBlockMode(cfg.BlocklistMode)
+		pipeline.Add(utils.BlocklistStage(c.Blocklist, mode))
+		a.Log("main").Infof("Loaded blocklist of %d entries", c.Blocklist.Len())
+	}
+	return c, nil
+}
+
+// NewBashSource creates source of the configured corpus file.
+func (a *App) NewBashSource() (*BashSource, error) {
+	return a.NewBashSourceFile(a.Config.Corpus.File)
+}
+
+// NewBashSourceFile creates source of the corpus file filtered and
+// weighted by configuration.
+func (a *App) NewBashSourceFile(path string) (*BashSource, error) {
+	cfg := a.Config.Corpus
+	filter, err := quoteFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	weight, err := utils.ParseRatingWeight(cfg.RatingWeight)
+	if err != nil {
+		return nil, fmt.Errorf("parse rating weight: %w", err)
+	}
+	l := a.Log("utils")
+	bp := utils.NewBashParser(path, a.Logger.Level)
+	bp.SetLogger(l)
+	bp.SetFilter(filter)
+	bp.SetPanicHandler(a.Reporter.ReportPanic)
+	switch a.Config.Log.Progress {
+	case "tty":
+		bp.SetProgress(utils.NewTTYProgress(os.Stderr))
+	case "log":
+		bp.SetProgress(utils.NewLogProgress(l, 5*time.Second))
+	case "none":
+		bp.SetProgress(nil)
+	default:
+		bp.SetProgress(utils.NewProgress(l))
+	}
+	return &BashSource{Parser: bp, Weight: weight}, nil
+}
+
+// Train creates generator of the configured backend, trains it on the
+// source and stores it by name. Prepare is called for each text before
+// learning, it may be nil. Errors of the source are logged and reported.
+func (a *App) Train(ctx context.Context, name string, src Source, prepare func(string) (string, bool)) (Generator, error) {
+	var templates []string
+	if path := a.Config.Generation.TemplateFile; path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open templates: %w", err)
+		}
+		templates, err = LoadTemplates(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read templates %s: %w", path, err)
+		}
+	}
+	g, err := New(Options{
+		Backend:   a.Config.Generation.Backend,
+		Order:     a.Config.Chain.Order,
+		KeepCase:  a.Config.Tokenizer.KeepCase,
+		CharOrder: a.Config.Chain.CharOrder,
+		Workers:   a.Config.Corpus.Workers,
+		Prepare:   prepare,
+		Logger:    a.Log("markov").WithField("model", name),
+		Templates: templates,
+		OnPanic:   a.Reporter.ReportPanic,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := g.Train(ctx, reportingSource{src, a, name}); err != nil {
+		return nil, err
+	}
+	a.Models.Put(name, g)
+	return g, nil
+}
+
+// reportingSource logs and reports errors of the source instead of
+// passing them on.
+type reportingSource struct {
+	Source
+	app  *App
+	name string
+}
+
+func (s reportingSource) Texts(ctx context.Context) (<-chan markov.Text, <-chan error) {
+	texts, srcErrc := s.Source.Texts(ctx)
+	errc := make(chan error)
+	l := s.app.Log("main").WithField("model", s.name)
+	go func() {
+		defer s.app.Reporter.ReportPanic()
+		defer close(errc)
+		for err := range srcErrc {
+			l.WithError(err).Error("got error from source")
+			s.app.Reporter.Report(err, map[string]interface{}{"model": s.name})
+		}
+	}()
+	return texts, errc
+}
+
+// quoteFilter returns filter of quotes set by configuration.
+func quoteFilter(cfg CorpusConfig) (utils.QuoteFilter, error) {
+	f := utils.QuoteFilter{MinNumber: cfg.MinNumber, MaxNumber: cfg.MaxNumber}
+	var err error
+	if cfg.From != "" {
+		if f.From, _, err = utils.ParseDate(cfg.From); err != nil {
+			return f, fmt.Errorf("from %q: %w", cfg.From, err)
+		}
+	}
+	if cfg.To != "" {
+		var g utils.Granularity
+		if f.To, g, err = utils.ParseDate(cfg.To); err != nil {
+			return f, fmt.Errorf("to %q: %w", cfg.To, err)
+		}
+		// date includes the whole year, month or day it sets
+		f.To = g.End(f.To)
+	}
+	return f, nil
+}
@@ -0,0 +1,36 @@
+package phrasegen
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuoteFilter(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{"2005", "2005", time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2005, 12, 31, 23, 59, 59, 999999999, time.UTC)},
+		{"2005-06", "2005-06", time.Date(2005, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2005, 6, 30, 23, 59, 59, 999999999, time.UTC)},
+		{"07.06.2005", "07.06.2005", time.Date(2005, 6, 7, 0, 0, 0, 0, time.UTC), time.Date(2005, 6, 7, 23, 59, 59, 999999999, time.UTC)},
+		{"", "2005-06-07 12:30", time.Time{}, time.Date(2005, 6, 7, 12, 30, 59, 999999999, time.UTC)},
+		{"", "", time.Time{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		f, err := quoteFilter(CorpusConfig{From: tt.from, To: tt.to, MinNumber: 1, MaxNumber: 2})
+		if err != nil {
+			t.Errorf("quoteFilter(%q, %q): %v", tt.from, tt.to, err)
+			continue
+		}
+		if !f.From.Equal(tt.wantFrom) || !f.To.Equal(tt.wantTo) {
+			t.Errorf("quoteFilter(%q, %q) = [%v, %v], want [%v, %v]", tt.from, tt.to, f.From, f.To, tt.wantFrom, tt.wantTo)
+		}
+		if f.MinNumber != 1 || f.MaxNumber != 2 {
+			t.Errorf("quoteFilter(%q, %q) lost number limits", tt.from, tt.to)
+		}
+	}
+	if _, err := quoteFilter(CorpusConfig{To: "вчера"}); err == nil {
+		t.Error("unknown date must fail")
+	}
+}
@@ -0,0 +1,269 @@
+package phrasegen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ferux/phraseGen/markov"
+	"github.com/ferux/phraseGen/utils"
+)
+
+const (
+	// replyKeywords is amount of message keywords a reply is built around.
+	replyKeywords = 3
+	// replyCandidates is minimal amount of candidates searched for keywords.
+	replyCandidates = 20
+)
+
+// chainGenerator is Generator backed by markov chain.
+type chainGenerator struct {
+	opts     Options
+	pipeline *utils.Pipeline
+	filler   *utils.PlaceholderFiller
+
+	// train serializes merging of trained chains
+	train sync.Mutex
+	mu    sync.RWMutex
+	// chain is replaced, never changed, so it may be used without lock
+	chain *markov.Chain
+}
+
+// newChainGenerator creates generator backed by word markov chain.
+// Order of the chain makes it n-gram model of order+1 words.
+func newChainGenerator(opts Options) (Generator, error) {
+	pipeline, err := utils.ParsePipeline(strings.Join(opts.Pipeline, ","))
+	if err != nil {
+		return nil, err
+	}
+	if opts.Blocklist != nil {
+		pipeline.Add(utils.BlocklistStage(opts.Blocklist, utils.BlockText))
+	}
+	g := &chainGenerator{
+		opts:     opts,
+		pipeline: pipeline,
+		filler:   utils.NewPlaceholderFiller(),
+	}
+	g.chain = g.newChain()
+	return g, nil
+}
+
+func (g *chainGenerator) newChain() *markov.Chain {
+	c := markov.NewChainOrder(g.opts.Order)
+	c.SetKeepCase(g.opts.KeepCase)
+	c.SetLogger(g.opts.Logger)
+	return c
+}
+
+// Train learns the source texts. Errors of the source don't stop training,
+// the first of them is returned.
+func (g *chainGenerator) Train(ctx context.Context, src Source) error {
+	texts, errc := src.Texts(ctx)
+	firstErr := make(chan error, 1)
+	go func() {
+		var first error
+		for err := range errc {
+			if first == nil {
+				first = err
+			}
+		}
+		firstErr <- first
+	}()
+	// loaded chain may differ from options
+	g.mu.RLock()
+	order, keepCase := g.chain.Order(), g.chain.KeepCase()
+	g.mu.RUnlock()
+	c, ts := markov.Train(texts, markov.TrainOptions{
+		Workers:  g.opts.Workers,
+		Order:    order,
+		KeepCase: keepCase,
+		Prepare:  g.prepare,
+		OnPanic:  g.opts.OnPanic,
+	})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.SetLogger(g.opts.Logger)
+	if err := g.merge(c); err != nil {
+		// another chain was loaded during training
+		return fmt.Errorf("merge trained chain: %w", err)
+	}
+	if g.opts.Logger != nil {
+		g.opts.Logger.Infof("Trained on %d texts (%d skipped, %d failed) by %d workers for %s: %.0f texts/s, %.0f KB/s (merging %s)",
+			ts.Texts, ts.Skipped, ts.Failed, ts.Workers, ts.Duration, ts.TextsPerSecond(), ts.BytesPerSecond()/1024, ts.Merging)
+	}
+	return <-firstErr
+}
+
+// merge replaces the chain with its copy merged with the trained chain,
+// so the current chain stays intact for its users.
+func (g *chainGenerator) merge(c *markov.Chain) error {
+	g.train.Lock()
+	defer g.train.Unlock()
+	for {
+		cur := g.Chain()
+		merged := c
+		if cur.GetTotalRecords() > 0 {
+			merged = markov.NewChainOrder(cur.Order())
+			merged.SetKeepCase(cur.KeepCase())
+			merged.SetLogger(g.opts.Logger)
+			if err := merged.Merge(cur); err != nil {
+				return err
+			}
+			if err := merged.Merge(c); err != nil {
+				return err
+			}
+			merged.CalculateCells()
+		}
+		g.mu.Lock()
+		// chain may be loaded meanwhile
+		if g.chain == cur {
+			g.chain = merged
+			g.mu.Unlock()
+			return nil
+		}
+		g.mu.Unlock()
+	}
+}
+
+// prepare runs the pipeline and Prepare of options.
+func (g *chainGenerator) prepare(text string) (string, bool) {
+	text, ok := g.pipeline.Process(text)
+	if ok && g.opts.Prepare != nil {
+		text, ok = g.opts.Prepare(text)
+	}
+	return text, ok
+}
+
+// Generate returns the best of generated candidates.
+func (g *chainGenerator) Generate(ctx context.Context, opts GenerateOptions) (Phrase, error) {
+	if err := ctx.Err(); err != nil {
+		return Phrase{}, err
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.chain.GetTotalRecords() == 0 {
+		return Phrase{}, ErrNotTrained
+	}
+	mo := markov.GenerateOptions{
+		Candidates:    opts.Candidates,
+		Ranker:        opts.Ranker,
+		MaxWords:      opts.MaxWords,
+		MaxRepeat:     opts.MaxRepeat,
+		NoRepeatNgram: opts.NoRepeatNgram,
+		RepeatPenalty: opts.RepeatPenalty,
+		StopOnCycle:   opts.StopOnCycle,
+		Start:         opts.Start,
+		Temperature:   opts.Temperature,
+		Seed:          opts.Seed,
+		Trace:         opts.Trace,
+	}
+	if mo.Ranker == nil {
+		mo.Ranker = markov.LikelihoodRanker{Normalize: true}
+	}
+	bl, accept, minWords := g.opts.Blocklist, opts.Accept, opts.MinWords
+	if bl != nil || accept != nil || minWords > 0 {
+		mo.Accept = func(words []string) bool {
+			if len(words) < minWords {
+				return false
+			}
+			if bl != nil && bl.Contains(strings.Join(words, " ")) {
+				return false
+			}
+			return accept == nil || accept(words)
+		}
+	}
+	cand, err := g.chain.GenerateWith(mo)
+	if err != nil {
+		return Phrase{}, err
+	}
+	p := Phrase{Text: cand.String(), Words: cand.Words, Score: cand.Score, Trace: cand.Trace}
+	if opts.FillPlaceholders {
+		p.Text = g.filler.Fill(p.Text)
+	}
+	return p, nil
+}
+
+// Reply generates phrase starting with the most relevant keyword of the
+// message. If no keyword starts a phrase, it looks for phrases containing
+// any of them and falls back to a random phrase.
+func (g *chainGenerator) Reply(ctx context.Context, message string, opts GenerateOptions) (Phrase, error) {
+	keywords := make([]string, 0, replyKeywords)
+	for _, k := range g.Chain().Keywords(message, replyKeywords) {
+		keywords = append(keywords, k.Word)
+	}
+	for _, k := range keywords {
+		o := opts
+		o.Start = k
+		p, err := g.Generate(ctx, o)
+		if err == nil {
+			p.Keywords = []string{k}
+			return p, nil
+		}
+		if ctx.Err() != nil {
+			return Phrase{}, ctx.Err()
+		}
+	}
+	if len(keywords) > 0 {
+		o := opts
+		o.Ranker = markov.KeywordRanker{Keywords: keywords}
+		if o.Candidates < replyCandidates {
+			o.Candidates = replyCandidates
+		}
+		o.Accept = func(words []string) bool {
+			return len(keywordsIn(words, keywords)) > 0 && (opts.Accept == nil || opts.Accept(words))
+		}
+		p, err := g.Generate(ctx, o)
+		if err == nil {
+			p.Keywords = keywordsIn(p.Words, keywords)
+			return p, nil
+		}
+		if ctx.Err() != nil {
+			return Phrase{}, ctx.Err()
+		}
+	}
+	return g.Generate(ctx, opts)
+}
+
+// Save writes the chain.
+func (g *chainGenerator) Save(w io.Writer) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.chain.Save(w)
+}
+
+// Load replaces the chain. Order and case of the saved chain are kept.
+func (g *chainGenerator) Load(r io.Reader) error {
+	c, err := markov.Load(r)
+	if err != nil {
+		return err
+	}
+	c.SetLogger(g.opts.Logger)
+	g.mu.Lock()
+	g.chain = c
+	g.mu.Unlock()
+	return nil
+}
+
+// Chain returns the current chain.
+func (g *chainGenerator) Chain() *markov.Chain {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.chain
+}
+
+// keywordsIn returns keywords present in words.
+func keywordsIn(words, keywords []string) []string {
+	found := make([]string, 0)
+	for _, k := range keywords {
+		for _, w := range words {
+			if strings.EqualFold(w, k) {
+				found = append(found, k)
+				break
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,174 @@
+package phrasegen
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ferux/phraseGen/markov"
+	"github.com/ferux/phraseGen/utils"
+)
+
+func init() {
+	RegisterBackend("char", newCharGenerator)
+}
+
+// defaultCharAttempts limits tries to invent acceptable word.
+const defaultCharAttempts = 1000
+
+// VocabularyLearner is implemented by generators able to learn words
+// of the word chain.
+type VocabularyLearner interface {
+	LearnVocabulary(c *markov.Chain) error
+}
+
+// charGenerator is Generator inventing words by character markov chain.
+type charGenerator struct {
+	opts     Options
+	pipeline *utils.Pipeline
+
+	mu  sync.RWMutex
+	cc  *markov.CharChain
+	rmu sync.Mutex
+	rnd *rand.Rand
+}
+
+// newCharGenerator creates generator of single words and nicknames.
+func newCharGenerator(opts Options) (Generator, error) {
+	pipeline, err := utils.ParsePipeline(strings.Join(opts.Pipeline, ","))
+	if err != nil {
+		return nil, err
+	}
+	if opts.CharOrder < 1 {
+		opts.CharOrder = 3
+	}
+	return &charGenerator{
+		opts:     opts,
+		pipeline: pipeline,
+		cc:       markov.NewCharChain(opts.CharOrder),
+		rnd:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Train learns distinct words of the source texts. Errors of the source
+// don't stop training, the first of them is returned.
+func (g *charGenerator) Train(ctx context.Context, src Source) error {
+	texts, errc := src.Texts(ctx)
+	firstErr := make(chan error, 1)
+	go func() {
+		var first error
+		for err := range errc {
+			if first == nil {
+				first = err
+			}
+		}
+		firstErr <- first
+	}()
+	g.mu.Lock()
+	for t := range texts {
+		text, ok := g.pipeline.Process(t.Text)
+		if ok && g.opts.Prepare != nil {
+			text, ok = g.opts.Prepare(text)
+		}
+		if !ok {
+			continue
+		}
+		w := t.Weight
+		if w == 0 {
+			w = 1
+		}
+		if err := g.cc.AddText(text, w); err != nil && g.opts.Logger != nil {
+			g.opts.Logger.Warnf("can't learn %q: %v", text, err)
+		}
+	}
+	g.cc.Calculate()
+	words := g.cc.Len()
+	g.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if g.opts.Logger != nil {
+		g.opts.Logger.Infof("Learned %d words", words)
+	}
+	return <-firstErr
+}
+
+// LearnVocabulary learns distinct words of the word chain.
+func (g *charGenerator) LearnVocabulary(c *markov.Chain) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.cc.AddVocabulary(c); err != nil {
+		return err
+	}
+	g.cc.Calculate()
+	return nil
+}
+
+// Generate invents a word.
+func (g *charGenerator) Generate(ctx context.Context, opts GenerateOptions) (Phrase, error) {
+	if err := ctx.Err(); err != nil {
+		return Phrase{}, err
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.cc.Len() == 0 {
+		return Phrase{}, ErrNotTrained
+	}
+	// each word is checked here, so attempts of the chain are counted one by one
+	co := markov.CharOptions{
+		MinLength:   opts.MinLength,
+		MaxLength:   opts.MaxLength,
+		RejectKnown: opts.RejectKnown,
+		Attempts:    1,
+	}
+	attempts := opts.Attempts
+	if attempts < 1 {
+		attempts = defaultCharAttempts
+	}
+	bl := g.opts.Blocklist
+	rnd := g.rnd
+	if opts.Seed != 0 {
+		rnd = rand.New(rand.NewSource(opts.Seed))
+	} else {
+		g.rmu.Lock()
+		defer g.rmu.Unlock()
+	}
+	for i := 0; i < attempts; i++ {
+		w, err := g.cc.GenerateWord(rnd, co)
+		if errors.Is(err, markov.ErrRejected) {
+			continue
+		}
+		if err != nil {
+			return Phrase{}, err
+		}
+		words := []string{w}
+		if (bl != nil && bl.Contains(w)) || (opts.Accept != nil && !opts.Accept(words)) {
+			continue
+		}
+		return Phrase{Text: w, Words: words}, nil
+	}
+	return Phrase{}, &markov.AttemptsError{Attempts: attempts}
+}
+
+// Save writes the character chain.
+func (g *charGenerator) Save(w io.Writer) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.cc.Save(w)
+}
+
+// Load replaces the character chain.
+func (g *charGenerator) Load(r io.Reader) error {
+	cc, err := markov.LoadCharChain(r)
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	g.cc = cc
+	g.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,36 @@
+package phrasegen
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ferux/phraseGen/markov"
+)
+
+func TestCharGeneratorAttempts(t *testing.T) {
+	ctx := context.Background()
+	g, err := New(Options{Backend: "char", CharOrder: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Train(ctx, TextSource{"кот котик котенок кошка"}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := g.Generate(ctx, GenerateOptions{MinLength: 3, MaxLength: 10, Seed: 3})
+	if err != nil || len(p.Words) != 1 {
+		t.Fatalf("Generate() = %v, %v", p, err)
+	}
+	accepted := 0
+	_, err = g.Generate(ctx, GenerateOptions{MaxLength: 10, Attempts: 50, Accept: func([]string) bool {
+		accepted++
+		return false
+	}})
+	var aerr *markov.AttemptsError
+	if !errors.As(err, &aerr) || aerr.Attempts != 50 {
+		t.Errorf("Generate() of rejected words error = %v, want AttemptsError of 50 attempts", err)
+	}
+	if accepted > 50 {
+		t.Errorf("Accept called %d times, want at most 50", accepted)
+	}
+}
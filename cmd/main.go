@@ -1,150 +1,281 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
-	"strconv"
-	"bufio"
 	"fmt"
 	"os"
 	"strings"
 
-	"github.com/airbrake/gobrake"
-	"github.com/sirupsen/logrus"
 	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
 
 	"github.com/ferux/phraseGen"
-	"github.com/ferux/phrasegen/markov"
-	"github.com/ferux/phrasegen/utils"
+	"github.com/ferux/phraseGen/markov"
+	"github.com/ferux/phraseGen/utils"
 )
 
 func init() {
-	var err error
-	err = godotenv.Load()
-	if err != nil {
-		panic(err)
+	defaults := phrasegen.DefaultConfiguration()
+	flag.StringVar(&configPath, "config", os.Getenv("GO_CONFIG"), "Path to configuration file (json, yaml or toml)")
+	configFlags = defaults.RegisterFlags(flag.CommandLine)
+	flag.StringVar(&exportFormat, "export", "", "Export chain in given format (dot or graphml) and exit")
+	flag.StringVar(&exportOut, "export-out", "", "Path to export file, stdout if empty")
+	flag.StringVar(&exportOpts.Root, "export-root", "", "Export only neighborhood of the word")
+	flag.IntVar(&exportOpts.Depth, "export-depth", 1, "Depth of exported neighborhood")
+	flag.Uint64Var(&exportOpts.MinCount, "export-min", 1, "Skip transitions seen fewer times")
+	flag.IntVar(&statsTop, "stats", 0, "Print chain statistics with given amount of top words and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [config print]\n", os.Args[0])
+		flag.PrintDefaults()
 	}
+}
 
-	if fpath = os.Getenv("GO_FILE"); len(fpath) == 0 {
-		fpath = *flag.String("file", "", "Path to file")
-	}
+var (
+	// configPath path to configuration file
+	configPath string
+	// configFlags flags overriding configuration
+	configFlags *phrasegen.Flags
 
-	flag.Parse()
+	// statsTop amount of top words in statistics, zero disables it
+	statsTop int
 
-	c := phrasegen.Configuration{}
-	c.ErrbitHost = os.Getenv("GO_ERRBIT_HOST")
-	c.ErrbitID, err = strconv.ParseInt(os.Getenv("GO_ERRBIT_ID"), 10, 64)
-	
-	if err != nil {
-		panic(err)
-	}
-	c.ErrbitKey = os.Getenv("GO_ERRBIT_KEY")
-	phrasegen.Config = c
-	phrasegen.Environment = os.Getenv("GO_ENV")
-	phrasegen.Logger = logrus.New()
-
-	phrasegen.Notifier = gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
-		Host:        phrasegen.Config.ErrbitHost,
-		ProjectId:   phrasegen.Config.ErrbitID,
-		ProjectKey:  phrasegen.Config.ErrbitKey,
-		Environment: phrasegen.Environment,
-		Revision:    phrasegen.Revision,
-	})
+	// exportFormat format of exported chain, empty disables export
+	exportFormat string
+	// exportOut path to exported file
+	exportOut string
+	// exportOpts limits exported part of chain
+	exportOpts markov.ExportOptions
+)
 
-	phrasegen.Notifier.AddFilter(func(n *gobrake.Notice) *gobrake.Notice {
-		n.Params = map[string]interface{}{
-			"version":     phrasegen.Version,
-			"revision":    phrasegen.Revision,
-			"environment": phrasegen.Environment,
+// loadConfig returns configuration overridden by file, env and flags
+// in the order of increasing priority.
+func loadConfig() (phrasegen.Configuration, error) {
+	c := phrasegen.DefaultConfiguration()
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		return c, err
+	}
+	if configPath != "" {
+		if err := c.LoadFile(configPath); err != nil {
+			return c, err
 		}
-		return n
-	})
+	}
+	if err := c.ApplyEnv(os.LookupEnv); err != nil {
+		return c, err
+	}
+	return c, configFlags.Apply(&c)
+}
 
-	l = phrasegen.Logger.WithFields(logrus.Fields{
-		"version":  phrasegen.Version,
-		"revision": phrasegen.Revision,
-		"pkg": "main",
-		"fn": "main",
-	})
-	notifier = phrasegen.Notifier
+// fatalConfig reports configuration error and exits.
+func fatalConfig(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
 }
 
-var (
-	notifier *gobrake.Notifier
-	// l is for logger
-	l *logrus.Entry
+func main() {
+	flag.Parse()
+	cfg, err := loadConfig()
+	if err != nil {
+		fatalConfig(err)
+	}
+	if flag.Arg(0) == "config" {
+		if flag.Arg(1) != "print" {
+			fatalConfig(fmt.Errorf("unknown command %q", strings.Join(flag.Args(), " ")))
+		}
+		if err := cfg.Print(os.Stdout); err != nil {
+			fatalConfig(err)
+		}
+		if err := cfg.Validate(); err != nil {
+			fatalConfig(err)
+		}
+		return
+	}
+	if cfg.Corpus.File == "" {
+		fatalConfig(errors.New("corpus.file is required"))
+	}
 
-	// fpath path to dictionary or text
-	fpath string
-)
+	app, err := phrasegen.NewApp(cfg)
+	if err != nil {
+		fatalConfig(err)
+	}
+	l := app.Log("main").WithField("fn", "main")
+	closeApp := func() {
+		if err := app.Close(); err != nil {
+			l.WithError(err).Error("can't close application")
+		}
+	}
+	// Fatal exits skipping deferred calls, so pending reports are sent here
+	logrus.RegisterExitHandler(closeApp)
+	defer closeApp()
+	defer app.Reporter.ReportPanic()
+	run(app, l)
+}
 
-func main() {
+// run trains the chain and generates phrases for lines of stdin or
+// HTTP requests.
+func run(app *phrasegen.App, l *logrus.Entry) {
 	l.Info("Started")
-	defer notifier.NotifyOnPanic()
-	if phrasegen.Environment == "dev" {
-		l.WithField("Configuration", phrasegen.Config)
+	if app.Config.Environment == "development" {
+		l.WithField("Configuration", app.Config.Masked()).Debug("Loaded configuration")
 	}
 
+	gen := app.Config.Generation
+	var novelty *markov.NoveltyRanker
+	if gen.Rank == "novelty" {
+		novelty = markov.NewNoveltyRanker(3)
+	}
+	ctx := context.Background()
+	g, corpus, err := trainModel(ctx, app, novelty, l)
+	if err != nil {
+		l.WithError(err).Fatal("can't train model")
+	}
+	if corpus.Dedup != nil && app.Config.Corpus.DedupeReport != "" {
+		if err := writeDedupeReport(corpus.Dedup, app.Config.Corpus.DedupeReport); err != nil {
+			l.WithError(err).Error("can't write dedupe report")
+		}
+	}
+	if statsTop > 0 || exportFormat != "" {
+		cp, ok := g.(phrasegen.ChainProvider)
+		if !ok {
+			l.Fatalf("backend %s has no chain to inspect", app.Config.Generation.Backend)
+		}
+		c := cp.Chain()
+		if statsTop > 0 {
+			printStats(os.Stdout, c.Stats(statsTop))
+			return
+		}
+		if err := exportChain(c, l); err != nil {
+			l.WithError(err).Error("can't export chain")
+			app.Reporter.Report(err, map[string]interface{}{"format": exportFormat})
+		}
+		return
+	}
 
-	bp := utils.NewBashParser(fpath, logrus.InfoLevel)
-	msgc, errc := bp.Start()
-	go func() {
-
-		l.Info("Started listening to errors")
-		for err := range errc {
-			l.WithError(err).Error("got error from parser")
+	ph := &phraser{g: g, blocklist: corpus.Blocklist, mode: gen.BlocklistMode}
+	opts := generateOptions(gen)
+	opts.Ranker = newRanker(gen, novelty)
+	if app.Config.Server.Enabled {
+		generate := func(ctx context.Context, message string, trace bool) (phrasegen.Phrase, error) {
+			o := opts
+			o.Trace = trace
+			return ph.phrase(ctx, message, o)
 		}
-	}()
+		if err := serve(app, generate, l); err != nil {
+			l.WithError(err).Fatal("can't serve HTTP")
+		}
+		return
+	}
 
-	c := markov.NewChain()
+	l.Println("Ready to accept messages")
+	r := newREPL(app, ph, opts, novelty, l)
+	if err := r.run(ctx, os.Stdin, os.Stdout); err != nil {
+		l.WithError(err).Error("can't read from stdin")
+	}
+}
+
+// trainModel prepares the corpus and trains the default generator on it.
+func trainModel(ctx context.Context, app *phrasegen.App, novelty *markov.NoveltyRanker, l *logrus.Entry) (phrasegen.Generator, *phrasegen.Corpus, error) {
+	corpus, err := app.NewCorpus()
+	if err != nil {
+		return nil, nil, fmt.Errorf("prepare corpus: %w", err)
+	}
+	src, err := app.NewBashSource()
+	if err != nil {
+		return nil, nil, fmt.Errorf("create source: %w", err)
+	}
 	l.Info("Ranging throught channel")
-	for msg := range msgc {
-		_ = c.ParseText(msg)
+	g, err := app.Train(ctx, phrasegen.DefaultGenerator, src, func(text string) (string, bool) {
+		text, ok := corpus.Pipeline.Process(text)
+		if ok && novelty != nil {
+			novelty.Add(text)
+		}
+		return text, ok
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, sc := range corpus.Pipeline.Counters() {
+		l.Infof("Stage %s: %d in, %d changed, %d dropped", sc.Name, sc.In, sc.Changed, sc.Dropped)
 	}
-	c.CalculateCells()
-	// c.Iterate()
+	return g, corpus, nil
+}
 
-	l.Println("Ready to accept messages")
-	sc := bufio.NewReader(os.Stdin)
-	for {
-		msg, err := sc.ReadString('\n')
-		if err != nil {
-			l.WithError(err).Error("can't read from stdin")
-			break
+// rejectAttempts limits generations of a phrase in reject mode.
+const rejectAttempts = 10
+
+// phraser generates phrases by the model rejecting or masking blocked words
+// according to mode.
+type phraser struct {
+	g         phrasegen.Generator
+	blocklist *utils.Blocklist
+	mode      string
+}
+
+// phrase replies to the message or generates a random phrase if it's empty.
+// In reject mode phrases with blocked words are generated again up to
+// rejectAttempts times.
+func (ph *phraser) phrase(ctx context.Context, message string, opts phrasegen.GenerateOptions) (phrasegen.Phrase, error) {
+	reject := ph.blocklist != nil && ph.mode == "reject"
+	if reject {
+		accept := opts.Accept
+		opts.Accept = func(words []string) bool {
+			return !ph.blocklist.Contains(strings.Join(words, " ")) && (accept == nil || accept(words))
 		}
-		if msg == "end\n" {
-			break
+	}
+	for i := 1; ; i++ {
+		p, err := ph.generate(ctx, message, opts)
+		if err == nil && reject && ph.blocklist.Contains(p.Text) {
+			err = markov.ErrRejected
 		}
-		msg = getNewMsg(c)
-		l.Println(msg)
+		if !reject || !errors.Is(err, markov.ErrRejected) || i == rejectAttempts || ctx.Err() != nil {
+			if err == nil && ph.blocklist != nil && ph.mode == "mask" {
+				p.Text = ph.blocklist.Mask(p.Text)
+			}
+			return p, err
+		}
+		if opts.Seed != 0 {
+			// the next seeds of candidates, the same seed rejects again
+			opts.Seed += int64(opts.Candidates) + 1
+		}
+	}
+}
+
+// generate replies to the message or generates a random phrase if it's empty.
+func (ph *phraser) generate(ctx context.Context, message string, opts phrasegen.GenerateOptions) (phrasegen.Phrase, error) {
+	if message == "" {
+		return ph.g.Generate(ctx, opts)
 	}
+	return phrasegen.Reply(ctx, ph.g, message, opts)
+}
 
-	os.Exit(0)
+// generateOptions returns options of phrase generation set by configuration.
+func generateOptions(gen phrasegen.GenerationConfig) phrasegen.GenerateOptions {
+	return phrasegen.GenerateOptions{
+		Candidates:       gen.Candidates,
+		MaxWords:         gen.MaxWords,
+		MaxRepeat:        gen.MaxRepeat,
+		NoRepeatNgram:    gen.NoRepeatNgram,
+		RepeatPenalty:    gen.RepeatPenalty,
+		StopOnCycle:      gen.StopOnCycle,
+		Temperature:      gen.Temperature,
+		FillPlaceholders: gen.FillPlaceholders,
+		MinLength:        gen.MinLength,
+		MaxLength:        gen.MaxLength,
+		RejectKnown:      gen.RejectKnown,
+	}
 }
 
-func getNewMsg(c *markov.Chain) string {
-	txts := make([]string, 0)
-	prev := "*START*"
-	cnt := 0
-	for msg, err := c.GetNextWord(prev); ; msg, err = c.GetNextWord(prev) {
-		cnt++
-		t := msg.GetWord()
-		tp := msg.GetType()
-		txts = append(txts, t)
-		prev = t
-		if tp == markov.End {
-			break
-		}
-		if err != nil {
-			// fmt.Println(err)
-			break
-		}
-		if cnt > 30 {
-			break
-		}
-		// fmt.Printf("Word: %s\tType: %d\n", t, tp)
-	}
-	txt := strings.Join(txts[:len(txts)-1], " ")
-	txt = strings.Replace(txt, "*END*", ".", -1)
-	txt = txt + "."
-	return fmt.Sprintf("%s\n", txt)
+// newRanker returns ranker chosen by configuration.
+func newRanker(gen phrasegen.GenerationConfig, novelty *markov.NoveltyRanker) markov.Ranker {
+	switch gen.Rank {
+	case "length":
+		return markov.LengthRanker{Target: gen.RankLength}
+	case "novelty":
+		return novelty
+	case "keywords":
+		return markov.KeywordRanker{Keywords: gen.RankKeywords}
+	default:
+		return markov.LikelihoodRanker{Normalize: true}
+	}
 }
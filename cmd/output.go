@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ferux/phraseGen/markov"
+	"github.com/ferux/phraseGen/utils"
+)
+
+// writeDedupeReport writes removed duplicates to the file.
+func writeDedupeReport(d *utils.Deduplicator, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := d.WriteReport(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exportChain writes chain to file or stdout according to flags.
+func exportChain(c *markov.Chain, l *logrus.Entry) error {
+	w := os.Stdout
+	if exportOut != "" {
+		f, err := os.Create(exportOut)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				l.WithError(err).Error("can't close export file")
+			}
+		}()
+		w = f
+	}
+	switch exportFormat {
+	case "dot":
+		return c.WriteDOT(w, exportOpts)
+	case "graphml":
+		return c.WriteGraphML(w, exportOpts)
+	default:
+		return fmt.Errorf("unknown export format %q", exportFormat)
+	}
+}
+
+// printStats writes chain statistics to w.
+func printStats(w io.Writer, s markov.Stats) {
+	fmt.Fprintf(w, "Vocabulary:\t%d\n", s.Vocabulary)
+	fmt.Fprintf(w, "States:\t\t%d\n", s.States)
+	fmt.Fprintf(w, "Transitions:\t%d (%d distinct)\n", s.Transitions, s.Edges)
+	fmt.Fprintf(w, "Sentences:\t%d (%.2f words average)\n", s.Sentences, s.AvgSentenceLength)
+	fmt.Fprintf(w, "Dead ends:\t%d\n", s.DeadEnds)
+
+	branches := make([]int, 0, len(s.Branching))
+	for b := range s.Branching {
+		branches = append(branches, b)
+	}
+	sort.Ints(branches)
+	fmt.Fprintln(w, "Branching:")
+	for _, b := range branches {
+		fmt.Fprintf(w, "\t%6d cells: %d states\n", b, s.Branching[b])
+	}
+	fmt.Fprintln(w, "Top words:")
+	for _, wc := range s.TopWords {
+		fmt.Fprintf(w, "\t%20s\t%d\n", wc.Word, wc.Count)
+	}
+	fmt.Fprintln(w, "Top transitions:")
+	for _, t := range s.TopTransitions {
+		fmt.Fprintf(w, "\t%20s -> %-20s\t%d\n", t.From, t.To, t.Count)
+	}
+}
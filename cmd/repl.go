@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/ferux/phraseGen"
+	"github.com/ferux/phraseGen/markov"
+)
+
+// historySize limits amount of remembered lines.
+const historySize = 100
+
+// replHelp describes commands of the REPL.
+const replHelp = `Empty line or text generates a phrase, text is replied to in reply mode.
+Commands:
+  :seed [N|-]      show, set or disable seed making phrases reproducible
+  :start [word|-]  show, set or clear the first word of phrases
+  :temp [T]        show or set temperature, below 1 favours likely words
+  :order [N]       show order of the chain or retrain it with the new one
+  :stats [N]       print chain statistics with N top words (10 by default)
+  :explain         show path and probabilities of the last phrase
+  :save file       save the model
+  :load file       load the model
+  :train file      train the model on one more corpus file
+  :history         list previous lines, !N repeats line N and !! the last one
+  :help            print this help
+  :quit            exit, "end" works too
+`
+
+// repl is an interactive session generating phrases and tuning generation.
+type repl struct {
+	app     *phrasegen.App
+	ph      *phraser
+	opts    phrasegen.GenerateOptions
+	novelty *markov.NoveltyRanker
+	l       *logrus.Entry
+
+	// rnd produces seeds of phrases if seed is set
+	rnd     *rand.Rand
+	seed    int64
+	history []string
+	last    phrasegen.Phrase
+}
+
+// errQuit stops the REPL.
+var errQuit = errors.New("quit")
+
+// newREPL creates session generating phrases by ph with options opts.
+func newREPL(app *phrasegen.App, ph *phraser, opts phrasegen.GenerateOptions, novelty *markov.NoveltyRanker, l *logrus.Entry) *repl {
+	r := &repl{app: app, ph: ph, opts: opts, novelty: novelty, l: l}
+	r.setSeed(app.Config.Generation.Seed)
+	return r
+}
+
+// run reads lines of in until EOF or quit command. Prompt is printed
+// only if in is a terminal.
+func (r *repl) run(ctx context.Context, in io.Reader, out io.Writer) error {
+	prompt := ""
+	if f, ok := in.(*os.File); ok && terminal.IsTerminal(int(f.Fd())) {
+		prompt = "> "
+	}
+	sc := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, prompt)
+		if !sc.Scan() {
+			return sc.Err()
+		}
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "!") && line != "!" {
+			var err error
+			if line, err = r.recall(line[1:]); err != nil {
+				fmt.Fprintln(out, err)
+				continue
+			}
+			fmt.Fprintln(out, line)
+		}
+		if line != "" && line != ":history" {
+			r.remember(line)
+		}
+		if err := r.exec(ctx, line, out); err != nil {
+			if err == errQuit {
+				return nil
+			}
+			fmt.Fprintln(out, err)
+		}
+	}
+}
+
+// exec executes command or generates phrase for the line.
+func (r *repl) exec(ctx context.Context, line string, out io.Writer) error {
+	if line == "end" {
+		return errQuit
+	}
+	if !strings.HasPrefix(line, ":") {
+		return r.generate(ctx, line, out)
+	}
+	fields := strings.Fields(line[1:])
+	if len(fields) == 0 {
+		return errors.New("empty command, see :help")
+	}
+	cmd, arg := fields[0], strings.Join(fields[1:], " ")
+	switch cmd {
+	case "quit", "q":
+		return errQuit
+	case "help", "h":
+		fmt.Fprint(out, replHelp)
+	case "seed":
+		return r.seedCmd(arg, out)
+	case "start":
+		switch arg {
+		case "":
+			fmt.Fprintf(out, "start: %q\n", r.opts.Start)
+		case "-":
+			r.opts.Start = ""
+		default:
+			r.opts.Start = arg
+		}
+	case "temp":
+		if arg == "" {
+			fmt.Fprintf(out, "temperature: %g\n", r.opts.Temperature)
+			return nil
+		}
+		t, err := strconv.ParseFloat(arg, 64)
+		if err != nil || t <= 0 {
+			return fmt.Errorf("temperature must be positive number, got %q", arg)
+		}
+		r.opts.Temperature = t
+	case "order":
+		return r.orderCmd(ctx, arg, out)
+	case "stats":
+		c, err := r.chain()
+		if err != nil {
+			return err
+		}
+		top := 10
+		if arg != "" {
+			if top, err = strconv.Atoi(arg); err != nil || top < 1 {
+				return fmt.Errorf("amount of top words must be positive number, got %q", arg)
+			}
+		}
+		printStats(out, c.Stats(top))
+	case "explain":
+		return r.explain(out)
+	case "save", "load", "train":
+		if arg == "" {
+			return fmt.Errorf(":%s requires file", cmd)
+		}
+		return r.fileCmd(ctx, cmd, arg, out)
+	case "history":
+		for i, h := range r.history {
+			fmt.Fprintf(out, "%4d  %s\n", i+1, h)
+		}
+	default:
+		return fmt.Errorf("unknown command %q, see :help", cmd)
+	}
+	return nil
+}
+
+// generate prints phrase replying to the message in reply mode, random
+// phrase otherwise.
+func (r *repl) generate(ctx context.Context, message string, out io.Writer) error {
+	if !r.app.Config.Generation.Reply {
+		message = ""
+	}
+	opts := r.opts
+	_, opts.Trace = r.ph.g.(phrasegen.ChainProvider)
+	if r.rnd != nil {
+		for opts.Seed == 0 {
+			opts.Seed = r.rnd.Int63()
+		}
+	}
+	p, err := r.ph.phrase(ctx, message, opts)
+	if err != nil {
+		r.l.WithError(err).Error("can't generate phrase")
+		return nil
+	}
+	if len(p.Keywords) > 0 {
+		r.l.WithField("keywords", p.Keywords).Debug("Replied")
+	}
+	r.last = p
+	fmt.Fprintln(out, p.Text)
+	return nil
+}
+
+// seedCmd shows, sets or disables the seed.
+func (r *repl) seedCmd(arg string, out io.Writer) error {
+	switch arg {
+	case "":
+		if r.rnd == nil {
+			fmt.Fprintln(out, "seed: random")
+		} else {
+			fmt.Fprintf(out, "seed: %d\n", r.seed)
+		}
+	case "-":
+		r.setSeed(0)
+	default:
+		seed, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || seed == 0 {
+			return fmt.Errorf("seed must be non-zero number, got %q", arg)
+		}
+		r.setSeed(seed)
+	}
+	return nil
+}
+
+// setSeed restarts sequence of phrase seeds, zero makes phrases random.
+func (r *repl) setSeed(seed int64) {
+	r.seed, r.rnd = seed, nil
+	if seed != 0 {
+		r.rnd = rand.New(rand.NewSource(seed))
+	}
+}
+
+// orderCmd shows order of the chain or retrains the model with new order.
+func (r *repl) orderCmd(ctx context.Context, arg string, out io.Writer) error {
+	if arg == "" {
+		c, err := r.chain()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "order: %d\n", c.Order())
+		return nil
+	}
+	order, err := strconv.Atoi(arg)
+	if err != nil || order < 1 {
+		return fmt.Errorf("order must be positive number, got %q", arg)
+	}
+	prev := r.app.Config.Chain.Order
+	r.app.Config.Chain.Order = order
+	// novelty ranker learns texts of the new model only
+	novelty := r.novelty
+	if novelty != nil {
+		novelty = markov.NewNoveltyRanker(3)
+	}
+	g, _, err := trainModel(ctx, r.app, novelty, r.l)
+	if err != nil {
+		r.app.Config.Chain.Order = prev
+		return fmt.Errorf("retrain: %w", err)
+	}
+	r.ph.g = g
+	if novelty != nil {
+		r.novelty, r.opts.Ranker = novelty, novelty
+	}
+	r.last = phrasegen.Phrase{}
+	fmt.Fprintf(out, "retrained with order %d\n", order)
+	return nil
+}
+
+// explain prints trace of the last phrase. Phrase without trace is
+// explained by learned chances of the chain.
+func (r *repl) explain(out io.Writer) error {
+	if len(r.last.Words) == 0 {
+		return errors.New("no phrase to explain")
+	}
+	steps := r.last.Trace
+	if len(steps) == 0 {
+		c, err := r.chain()
+		if err != nil {
+			return err
+		}
+		if steps, err = c.Explain(r.last.Words); err != nil {
+			return fmt.Errorf("explain: %w", err)
+		}
+	}
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATE\tWORD\tCOUNT\tPROB\tALT\tLOGPROB")
+	for _, s := range steps {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.2f%%\t%d\t%.3f\n", s.State, s.Word, s.Count, s.Probability*100, s.Alternatives, s.LogProb)
+	}
+	return tw.Flush()
+}
+
+// fileCmd saves, loads or trains the model.
+func (r *repl) fileCmd(ctx context.Context, cmd, path string, out io.Writer) error {
+	switch cmd {
+	case "save":
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := r.ph.g.Save(f); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("save: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	case "load":
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := r.ph.g.Load(f); err != nil {
+			return fmt.Errorf("load: %w", err)
+		}
+		r.last = phrasegen.Phrase{}
+	case "train":
+		src, err := r.app.NewBashSourceFile(path)
+		if err != nil {
+			return err
+		}
+		if err := r.ph.g.Train(ctx, src); err != nil {
+			return fmt.Errorf("train: %w", err)
+		}
+	}
+	fmt.Fprintf(out, "%s: done\n", path)
+	return nil
+}
+
+// chain returns chain of the model.
+func (r *repl) chain() (*markov.Chain, error) {
+	cp, ok := r.ph.g.(phrasegen.ChainProvider)
+	if !ok {
+		return nil, fmt.Errorf("backend %s has no chain", r.app.Config.Generation.Backend)
+	}
+	return cp.Chain(), nil
+}
+
+// remember appends the line to history.
+func (r *repl) remember(line string) {
+	r.history = append(r.history, line)
+	if len(r.history) > historySize {
+		r.history = r.history[len(r.history)-historySize:]
+	}
+}
+
+// recall returns line of history by number, "!" means the last one.
+func (r *repl) recall(ref string) (string, error) {
+	if len(r.history) == 0 {
+		return "", errors.New("history is empty")
+	}
+	if ref == "!" {
+		return r.history[len(r.history)-1], nil
+	}
+	n, err := strconv.Atoi(ref)
+	if err != nil || n < 1 || n > len(r.history) {
+		return "", fmt.Errorf("no line %q in history", ref)
+	}
+	return r.history[n-1], nil
+}
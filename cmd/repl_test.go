@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ferux/phraseGen"
+)
+
+// newTestREPL returns session of markov model trained on small corpus.
+func newTestREPL(t *testing.T) *repl {
+	ctx := context.Background()
+	g, err := phrasegen.New(phrasegen.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := phrasegen.TextSource{"кот спит на диване.", "кот ест рыбу.", "пёс спит во дворе.", "пёс ест кость."}
+	if err := g.Train(ctx, src); err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	app := &phrasegen.App{Config: phrasegen.DefaultConfiguration(), Logger: logger}
+	opts := generateOptions(app.Config.Generation)
+	return newREPL(app, &phraser{g: g}, opts, nil, app.Log("main"))
+}
+
+func TestREPLExec(t *testing.T) {
+	ctx := context.Background()
+	r := newTestREPL(t)
+	tests := []struct {
+		line string
+		out  string
+		err  string
+	}{
+		{":explain", "", "no phrase to explain"},
+		{":seed", "seed: random\n", ""},
+		{":seed 42", "", ""},
+		{":seed", "seed: 42\n", ""},
+		{":seed 0", "", "seed must be non-zero number"},
+		{":start кот", "", ""},
+		{":start", "start: \"кот\"\n", ""},
+		{":temp 0.5", "", ""},
+		{":temp", "temperature: 0.5\n", ""},
+		{":temp -1", "", "temperature must be positive number"},
+		{":order", "order: 1\n", ""},
+		{":order 0", "", "order must be positive number"},
+		{":stats x", "", "amount of top words must be positive number"},
+		{":save", "", ":save requires file"},
+		{":", "", "empty command"},
+		{":bogus", "", "unknown command \"bogus\""},
+		{":quit", "", "quit"},
+		{"end", "", "quit"},
+	}
+	for _, tt := range tests {
+		out := &bytes.Buffer{}
+		err := r.exec(ctx, tt.line, out)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("exec(%q) error = %v, want %q", tt.line, err, tt.err)
+		}
+		if tt.out != "" && out.String() != tt.out {
+			t.Errorf("exec(%q) printed %q, want %q", tt.line, out.String(), tt.out)
+		}
+	}
+}
+
+func TestREPLGenerate(t *testing.T) {
+	ctx := context.Background()
+	r := newTestREPL(t)
+	phrases := make([]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		out := &bytes.Buffer{}
+		for _, line := range []string{":seed 7", ":start кот", ""} {
+			if err := r.exec(ctx, line, out); err != nil {
+				t.Fatalf("exec(%q) failed: %v", line, err)
+			}
+		}
+		phrases = append(phrases, out.String())
+	}
+	if !strings.HasPrefix(phrases[0], "кот ") || phrases[0] != phrases[1] {
+		t.Errorf("phrases of the same seed = %q, want the same phrases starting with кот", phrases)
+	}
+
+	out := &bytes.Buffer{}
+	if err := r.exec(ctx, ":explain", out); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); !strings.HasPrefix(lines[0], "STATE") || len(lines) < 3 {
+		t.Errorf(":explain printed %q, want header and steps", out.String())
+	}
+	out.Reset()
+	if err := r.exec(ctx, ":stats 2", out); err != nil || !strings.HasPrefix(out.String(), "Vocabulary:") {
+		t.Errorf(":stats printed %q, %v, want statistics", out.String(), err)
+	}
+}
+
+func TestREPLRecall(t *testing.T) {
+	r := newTestREPL(t)
+	if _, err := r.recall("!"); err == nil {
+		t.Error("recall of empty history must fail")
+	}
+	for i := 1; i <= historySize+2; i++ {
+		r.remember(fmt.Sprintf(":seed %d", i))
+	}
+	if len(r.history) != historySize {
+		t.Errorf("history holds %d lines, want %d", len(r.history), historySize)
+	}
+	tests := []struct {
+		ref  string
+		want string
+		ok   bool
+	}{
+		{"!", fmt.Sprintf(":seed %d", historySize+2), true},
+		{"1", ":seed 3", true},
+		{fmt.Sprint(historySize), fmt.Sprintf(":seed %d", historySize+2), true},
+		{"0", "", false},
+		{fmt.Sprint(historySize + 1), "", false},
+		{"x", "", false},
+	}
+	for _, tt := range tests {
+		got, err := r.recall(tt.ref)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("recall(%q) = %q, %v, want %q", tt.ref, got, err, tt.want)
+		}
+	}
+}
+
+func TestREPLRun(t *testing.T) {
+	r := newTestREPL(t)
+	in := strings.NewReader(":seed 5\n!!\n!9\n:history\nend\n:seed 6\n")
+	out := &bytes.Buffer{}
+	if err := r.run(context.Background(), in, out); err != nil {
+		t.Fatal(err)
+	}
+	want := ":seed 5\nno line \"9\" in history\n   1  :seed 5\n   2  :seed 5\n"
+	if out.String() != want {
+		t.Errorf("run printed %q, want %q", out.String(), want)
+	}
+	if r.seed != 5 {
+		t.Errorf("seed = %d, lines after end must be skipped", r.seed)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ferux/phraseGen"
+	"github.com/ferux/phraseGen/markov"
+)
+
+// generateFunc generates phrase replying to the message, random one if
+// the message is empty. Trace of generation is recorded if trace is set.
+type generateFunc func(ctx context.Context, message string, trace bool) (phrasegen.Phrase, error)
+
+// phraseResponse is a generated phrase returned by HTTP API.
+type phraseResponse struct {
+	Text  string   `json:"text"`
+	Words []string `json:"words"`
+	// Score is omitted if it's not finite, JSON has no infinities
+	Score    *float64 `json:"score,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+	// Trace is returned if requested by trace parameter
+	Trace []markov.Step `json:"trace,omitempty"`
+}
+
+// errorResponse is an error returned by HTTP API.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// serve runs HTTP API until SIGINT or SIGTERM:
+//
+//	GET /phrase returns random phrase
+//	GET or POST /reply?message=text returns phrase replying to the message,
+//	    POST also accepts JSON body {"message": "text"}
+//
+// Parameter trace=true adds steps of generation to the response.
+func serve(app *phrasegen.App, generate generateFunc, l *logrus.Entry) error {
+	srv := &http.Server{
+		Addr:         app.Config.Server.Address,
+		Handler:      newHandler(app, generate, l),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	errc := make(chan error, 1)
+	go func() {
+		l.WithField("address", srv.Addr).Info("Serving HTTP")
+		errc <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case sig := <-stop:
+		l.WithField("signal", sig).Info("Shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	}
+}
+
+// newHandler returns handler of HTTP API.
+func newHandler(app *phrasegen.App, generate generateFunc, l *logrus.Entry) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/phrase", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"method not allowed"})
+			return
+		}
+		writePhrase(w, r, app, generate, "", l)
+	})
+	mux.HandleFunc("/reply", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"method not allowed"})
+			return
+		}
+		message, err := requestMessage(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+		writePhrase(w, r, app, generate, message, l)
+	})
+	return mux
+}
+
+// requestMessage returns the message to reply from query, form or JSON body.
+func requestMessage(r *http.Request) (string, error) {
+	message := r.FormValue("message")
+	if message == "" && strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return "", errors.New("invalid JSON body")
+		}
+		message = body.Message
+	}
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return "", errors.New("message is required")
+	}
+	return message, nil
+}
+
+// writePhrase generates phrase and writes it as JSON.
+func writePhrase(w http.ResponseWriter, r *http.Request, app *phrasegen.App, generate generateFunc, message string, l *logrus.Entry) {
+	trace := false
+	if v := r.FormValue("trace"); v != "" {
+		var err error
+		if trace, err = strconv.ParseBool(v); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{"trace must be boolean"})
+			return
+		}
+	}
+	p, err := generate(r.Context(), message, trace)
+	if err != nil {
+		l.WithError(err).WithField("path", r.URL.Path).Error("can't generate phrase")
+		if !errors.Is(err, context.Canceled) {
+			app.Reporter.Report(err, map[string]interface{}{"path": r.URL.Path, "message": message})
+		}
+		writeJSON(w, http.StatusInternalServerError, errorResponse{err.Error()})
+		return
+	}
+	resp := phraseResponse{Text: p.Text, Words: p.Words, Keywords: p.Keywords, Trace: p.Trace}
+	if !math.IsInf(p.Score, 0) && !math.IsNaN(p.Score) {
+		resp.Score = &p.Score
+	}
+	if err := writeJSON(w, http.StatusOK, resp); err != nil {
+		l.WithError(err).WithField("path", r.URL.Path).Error("can't encode phrase")
+	}
+}
+
+// writeJSON writes v with the status. Values which can't be encoded are
+// replaced with internal error.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	buf := &bytes.Buffer{}
+	err := json.NewEncoder(buf).Encode(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		buf.Reset()
+		_ = json.NewEncoder(buf).Encode(errorResponse{"can't encode response"})
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+	return err
+}
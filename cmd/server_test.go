@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ferux/phraseGen"
+)
+
+// newTestHandler returns handler of API and the last message passed to generate.
+func newTestHandler(generate generateFunc) (http.Handler, *string) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	app := &phrasegen.App{Config: phrasegen.DefaultConfiguration(), Logger: logger, Reporter: phrasegen.NopReporter{}}
+	last := new(string)
+	h := newHandler(app, func(ctx context.Context, message string, trace bool) (phrasegen.Phrase, error) {
+		*last = message
+		return generate(ctx, message, trace)
+	}, app.Log("main"))
+	return h, last
+}
+
+func TestServerPhrase(t *testing.T) {
+	r := newTestREPL(t)
+	h, _ := newTestHandler(func(ctx context.Context, message string, trace bool) (phrasegen.Phrase, error) {
+		opts := r.opts
+		opts.Trace, opts.Seed = trace, 1
+		return r.ph.phrase(ctx, message, opts)
+	})
+	for _, target := range []string{"/phrase", "/phrase?trace=true", "/reply?message=кот&trace=1"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %q", target, rec.Code, rec.Body.String())
+		}
+		if _, ok := resp["score"].(float64); !ok || resp["text"] == "" {
+			t.Errorf("GET %s = %v, want text and score", target, resp)
+		}
+		if _, ok := resp["trace"]; ok != strings.Contains(target, "trace") {
+			t.Errorf("GET %s has trace %v", target, ok)
+		}
+	}
+}
+
+func TestServerInfiniteScore(t *testing.T) {
+	h, _ := newTestHandler(func(ctx context.Context, message string, trace bool) (phrasegen.Phrase, error) {
+		return phrasegen.Phrase{Text: "кот.", Words: []string{"кот"}, Score: math.Inf(-1)}, nil
+	})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/phrase", nil))
+	if want := "{\"text\":\"кот.\",\"words\":[\"кот\"]}\n"; rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Errorf("GET /phrase = %d %q, want 200 %q", rec.Code, rec.Body.String(), want)
+	}
+}
+
+func TestServerReply(t *testing.T) {
+	failing := errors.New("generation failed")
+	h, last := newTestHandler(func(ctx context.Context, message string, trace bool) (phrasegen.Phrase, error) {
+		if message == "fail" {
+			return phrasegen.Phrase{}, failing
+		}
+		return phrasegen.Phrase{Text: message + ".", Words: []string{message}}, nil
+	})
+	form := url.Values{"message": {" кот "}}.Encode()
+	tests := []struct {
+		method, target, contentType, body string
+		status                            int
+		message                           string
+	}{
+		{"GET", "/reply?message=кот", "", "", 200, "кот"},
+		{"POST", "/reply", "application/x-www-form-urlencoded", form, 200, "кот"},
+		{"POST", "/reply", "application/json", `{"message": "пёс"}`, 200, "пёс"},
+		{"POST", "/reply", "application/json", `{"message":`, 400, ""},
+		{"GET", "/reply", "", "", 400, ""},
+		{"GET", "/reply?message=+", "", "", 400, ""},
+		{"GET", "/reply?message=кот&trace=maybe", "", "", 400, ""},
+		{"GET", "/reply?message=fail", "", "", 500, "fail"},
+		{"PUT", "/reply?message=кот", "", "", 405, ""},
+		{"POST", "/phrase", "", "", 405, ""},
+	}
+	for _, tt := range tests {
+		*last = ""
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.status || *last != tt.message {
+			t.Errorf("%s %s = %d with message %q, want %d with %q", tt.method, tt.target, rec.Code, *last, tt.status, tt.message)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("%s %s content type %q", tt.method, tt.target, ct)
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Errorf("%s %s returned invalid JSON %q", tt.method, tt.target, rec.Body.String())
+		}
+		if _, ok := resp["error"]; ok != (tt.status != 200) {
+			t.Errorf("%s %s = %v, error expected %v", tt.method, tt.target, resp, tt.status != 200)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := writeJSON(rec, http.StatusOK, map[string]float64{"score": math.NaN()}); err == nil {
+		t.Error("writeJSON() of NaN must fail")
+	}
+	if want := "{\"error\":\"can't encode response\"}\n"; rec.Code != http.StatusInternalServerError || rec.Body.String() != want {
+		t.Errorf("writeJSON() = %d %q, want 500 %q", rec.Code, rec.Body.String(), want)
+	}
+}
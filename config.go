@@ -1,8 +1,13 @@
 package phrasegen
 
 import (
-	"github.com/airbrake/gobrake"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/sirupsen/logrus"
+
+	"github.com/ferux/phraseGen/utils"
 )
 
 var (
@@ -11,27 +16,228 @@ var (
 
 	// Revision of application
 	Revision string
+)
 
-	// Environment of application
-	Environment string
+// Configuration of the application. Each setting can be set in the
+// configuration file (by json name), by environment variable (env tag)
+// and by flag (flag tag), in the order of increasing priority.
+type Configuration struct {
+	Environment string           `json:"environment" env:"GO_ENV" flag:"env" usage:"Environment of application"`
+	Corpus      CorpusConfig     `json:"corpus"`
+	Chain       ChainConfig      `json:"chain"`
+	Tokenizer   TokenizerConfig  `json:"tokenizer"`
+	Generation  GenerationConfig `json:"generation"`
+	Server      ServerConfig     `json:"server"`
+	Log         LogConfig        `json:"log"`
+	Errors      ErrorsConfig     `json:"errors"`
+	Errbit      ErrbitConfig     `json:"errbit"`
+}
 
-	// Notifier is an app-wide error notifier
-	Notifier *gobrake.Notifier
+// CorpusConfig describes sources of texts and their preprocessing.
+type CorpusConfig struct {
+	File            string   `json:"file" env:"GO_FILE" flag:"file" usage:"Path to file"`
+	Pipeline        []string `json:"pipeline" env:"GO_PIPELINE" flag:"pipeline" usage:"Comma-separated preprocessing stages"`
+	ScrubPII        bool     `json:"scrub_pii" env:"GO_SCRUB_PII" flag:"scrub-pii" usage:"Replace personal data in corpus with placeholders, false removes pii stage from pipeline"`
+	From            string   `json:"from" env:"GO_FROM" flag:"from" usage:"Train on quotes published since the date"`
+	To              string   `json:"to" env:"GO_TO" flag:"to" usage:"Train on quotes published until the date"`
+	MinNumber       int64    `json:"min_number" env:"GO_MIN_NUMBER" flag:"min-number" usage:"Train on quotes with number not less than given"`
+	MaxNumber       int64    `json:"max_number" env:"GO_MAX_NUMBER" flag:"max-number" usage:"Train on quotes with number not greater than given"`
+	RatingWeight    string   `json:"rating_weight" env:"GO_RATING_WEIGHT" flag:"rating-weight" usage:"Weight of quotes by rating: none, linear[:scale], log or threshold:N"`
+	DedupeThreshold float64  `json:"dedupe_threshold" env:"GO_DEDUPE_THRESHOLD" flag:"dedupe-threshold" usage:"Similarity of texts considered near duplicates by dedupe stage"`
+	DedupeReport    string   `json:"dedupe_report" env:"GO_DEDUPE_REPORT" flag:"dedupe-report" usage:"Path to write report of removed duplicates"`
+	Blocklist       []string `json:"blocklist" env:"GO_BLOCKLIST" flag:"blocklist" usage:"Comma-separated paths to files with blocked words"`
+	BlocklistMode   string   `json:"blocklist_mode" env:"GO_BLOCKLIST_MODE" flag:"blocklist-train" usage:"Blocked words in corpus: off, quote, words or mask"`
+	Workers         int      `json:"workers" env:"GO_WORKERS" flag:"workers" usage:"Amount of training workers, amount of CPUs if zero"`
+}
 
-	// Logger is an app-wide logger
-	Logger *logrus.Logger
+// ChainConfig describes the markov chain.
+type ChainConfig struct {
+	Order     int `json:"order" env:"GO_CHAIN_ORDER" flag:"order" usage:"Amount of previous words each word depends on"`
+	CharOrder int `json:"char_order" env:"GO_CHAR_ORDER" flag:"char-order" usage:"Amount of previous characters each character depends on for char backend"`
+}
 
-	// Config is a settings for the application
-	Config Configuration
-)
+// TokenizerConfig describes splitting texts into words.
+type TokenizerConfig struct {
+	KeepCase bool `json:"keep_case" env:"GO_KEEP_CASE" flag:"keep-case" usage:"Don't lowercase words"`
+}
 
-// Configuration of the file
-type Configuration struct {
-	ErrbitHost string
-	ErrbitID   int64
-	ErrbitKey  string
+// GenerationConfig holds defaults of phrase generation.
+type GenerationConfig struct {
+	Backend          string   `json:"backend" env:"GO_BACKEND" flag:"backend" usage:"Model generating phrases"`
+	Candidates       int      `json:"candidates" env:"GO_CANDIDATES" flag:"candidates" usage:"Amount of candidates to generate for each phrase"`
+	Rank             string   `json:"rank" env:"GO_RANK" flag:"rank" usage:"Ranker of candidates: length, likelihood, novelty or keywords"`
+	RankLength       int      `json:"rank_length" env:"GO_RANK_LENGTH" flag:"rank-length" usage:"Preferred amount of words for length ranker"`
+	RankKeywords     []string `json:"rank_keywords" env:"GO_RANK_KEYWORDS" flag:"rank-keywords" usage:"Comma-separated keywords for keywords ranker"`
+	MaxWords         int      `json:"max_words" env:"GO_MAX_WORDS" flag:"max-words" usage:"Maximum amount of words in phrase"`
+	MaxRepeat        int      `json:"max_repeat" env:"GO_MAX_REPEAT" flag:"max-repeat" usage:"Maximum times the same word may appear in phrase, 0 is unlimited"`
+	NoRepeatNgram    int      `json:"no_repeat_ngram" env:"GO_NO_REPEAT_NGRAM" flag:"no-repeat-ngram" usage:"Ban repeating n-grams of given size, 0 disables"`
+	RepeatPenalty    float64  `json:"repeat_penalty" env:"GO_REPEAT_PENALTY" flag:"repeat-penalty" usage:"Multiplier of chance for already used words, 0 disables"`
+	StopOnCycle      bool     `json:"stop_on_cycle" env:"GO_STOP_ON_CYCLE" flag:"stop-on-cycle" usage:"Stop phrase once it starts repeating itself"`
+	Temperature      float64  `json:"temperature" env:"GO_TEMPERATURE" flag:"temperature" usage:"Below 1 favours likely words, above 1 flattens chances"`
+	Seed             int64    `json:"seed" env:"GO_SEED" flag:"seed" usage:"Seed making phrases reproducible, random if zero"`
+	FillPlaceholders bool     `json:"fill_placeholders" env:"GO_FILL_PLACEHOLDERS" flag:"fill-placeholders" usage:"Replace placeholders in generated phrases with synthetic values"`
+	MinLength        int      `json:"min_length" env:"GO_MIN_LENGTH" flag:"min-length" usage:"Minimum length of invented word for char backend"`
+	MaxLength        int      `json:"max_length" env:"GO_MAX_LENGTH" flag:"max-length" usage:"Maximum length of invented word for char backend"`
+	RejectKnown      bool     `json:"reject_known" env:"GO_REJECT_KNOWN" flag:"reject-known" usage:"Reject invented words existing in corpus for char backend"`
+	BlocklistMode    string   `json:"blocklist_mode" env:"GO_BLOCKLIST_OUTPUT" flag:"blocklist-output" usage:"Blocked words in phrases: off, reject or mask"`
+	TemplateFile     string   `json:"template_file" env:"GO_TEMPLATES" flag:"templates" usage:"File with templates for template backend, one per line"`
+	Reply            bool     `json:"reply" env:"GO_REPLY" flag:"reply" usage:"Reply to messages instead of generating random phrases"`
+}
+
+// ServerConfig describes HTTP server.
+type ServerConfig struct {
+	Enabled bool   `json:"enabled" env:"GO_SERVER" flag:"serve" usage:"Serve HTTP API instead of reading stdin"`
+	Address string `json:"address" env:"GO_SERVER_ADDRESS" flag:"server-address" usage:"Address of HTTP server"`
+}
+
+// LogConfig describes logging.
+type LogConfig struct {
+	Level    string `json:"level" env:"GO_LOG_LEVEL" flag:"log-level" usage:"Log level: debug, info, warn or error"`
+	Format   string `json:"format" env:"GO_LOG_FORMAT" flag:"log-format" usage:"Log format: text or json"`
+	Progress string `json:"progress" env:"GO_PROGRESS" flag:"progress" usage:"Progress reporting: auto, tty, log or none"`
+}
+
+// ErrorsConfig describes error reporting.
+type ErrorsConfig struct {
+	Reporter string `json:"reporter" env:"GO_REPORTER" flag:"reporter" usage:"Error reporter: auto, errbit, file or none"`
+	File     string `json:"file" env:"GO_REPORT_FILE" flag:"report-file" usage:"Path to file of error reports for file reporter"`
+}
+
+// ErrbitConfig describes error reporting to Errbit.
+type ErrbitConfig struct {
+	Host string `json:"host" env:"GO_ERRBIT_HOST" flag:"errbit-host" usage:"Errbit host"`
+	ID   int64  `json:"id" env:"GO_ERRBIT_ID" flag:"errbit-id" usage:"Errbit project ID"`
+	Key  string `json:"key" env:"GO_ERRBIT_KEY" flag:"errbit-key" usage:"Errbit project key"`
+}
+
+// configured reports whether all settings of Errbit are set.
+func (c ErrbitConfig) configured() bool {
+	return c.Host != "" && c.ID != 0 && c.Key != ""
+}
+
+// DefaultConfiguration returns configuration with default values.
+func DefaultConfiguration() Configuration {
+	return Configuration{
+		Environment: "development",
+		Corpus: CorpusConfig{
+			Pipeline:        []string{"dialog", "pii", "normalize", "dedupe"},
+			ScrubPII:        true,
+			RatingWeight:    "none",
+			DedupeThreshold: 0.8,
+			BlocklistMode:   "quote",
+		},
+		Chain: ChainConfig{Order: 1, CharOrder: 3},
+		Generation: GenerationConfig{
+			Backend:          DefaultBackend,
+			Candidates:       5,
+			Rank:             "likelihood",
+			RankLength:       10,
+			MaxWords:         30,
+			MaxRepeat:        2,
+			NoRepeatNgram:    2,
+			RepeatPenalty:    0.5,
+			StopOnCycle:      true,
+			Temperature:      1,
+			FillPlaceholders: true,
+			MinLength:        3,
+			MaxLength:        12,
+			RejectKnown:      true,
+			BlocklistMode:    "reject",
+		},
+		Server: ServerConfig{Address: ":8080"},
+		Log: LogConfig{
+			Level:    "info",
+			Format:   "text",
+			Progress: "auto",
+		},
+		Errors: ErrorsConfig{Reporter: "auto"},
+	}
 }
 
-/*
-	"github.com/namsral/flag"
-*/
+// ValidationError lists problems of the configuration.
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e, "; ")
+}
+
+// Validate checks the configuration. It returns ValidationError
+// listing all found problems.
+func (c *Configuration) Validate() error {
+	var errs ValidationError
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	for _, name := range c.Corpus.Pipeline {
+		check(oneOf(name, utils.StageNames()...),
+			"corpus.pipeline: unknown stage %q, known are %s", name, strings.Join(utils.StageNames(), ", "))
+	}
+	_, err := utils.ParseRatingWeight(c.Corpus.RatingWeight)
+	check(err == nil, "corpus.rating_weight: %v", err)
+	var from, to time.Time
+	if c.Corpus.From != "" {
+		from, _, err = utils.ParseDate(c.Corpus.From)
+		check(err == nil, "corpus.from %q: %v", c.Corpus.From, err)
+	}
+	if c.Corpus.To != "" {
+		var g utils.Granularity
+		to, g, err = utils.ParseDate(c.Corpus.To)
+		check(err == nil, "corpus.to %q: %v", c.Corpus.To, err)
+		to = g.End(to)
+	}
+	check(from.IsZero() || to.IsZero() || !to.Before(from),
+		"corpus.from %q is after corpus.to %q", c.Corpus.From, c.Corpus.To)
+	check(c.Corpus.MaxNumber == 0 || c.Corpus.MinNumber <= c.Corpus.MaxNumber,
+		"corpus.min_number %d is greater than corpus.max_number %d", c.Corpus.MinNumber, c.Corpus.MaxNumber)
+	check(c.Corpus.DedupeThreshold > 0 && c.Corpus.DedupeThreshold <= 1,
+		"corpus.dedupe_threshold must be in range (0, 1], got %g", c.Corpus.DedupeThreshold)
+	check(c.Corpus.Workers >= 0, "corpus.workers can't be negative")
+	check(oneOf(c.Corpus.BlocklistMode, "off", "quote", "words", "mask"),
+		"corpus.blocklist_mode must be off, quote, words or mask, got %q", c.Corpus.BlocklistMode)
+	check(c.Chain.Order >= 1, "chain.order must be positive, got %d", c.Chain.Order)
+	check(c.Chain.CharOrder >= 1, "chain.char_order must be positive, got %d", c.Chain.CharOrder)
+	check(oneOf(c.Generation.Backend, BackendNames()...),
+		"generation.backend must be one of %s, got %q", strings.Join(BackendNames(), ", "), c.Generation.Backend)
+	check(c.Generation.Backend != "template" || c.Generation.TemplateFile != "",
+		"generation.template_file is required by template backend")
+	check(c.Generation.Candidates >= 1, "generation.candidates must be positive, got %d", c.Generation.Candidates)
+	check(oneOf(c.Generation.Rank, "length", "likelihood", "novelty", "keywords"),
+		"generation.rank must be length, likelihood, novelty or keywords, got %q", c.Generation.Rank)
+	check(c.Generation.MaxWords >= 1, "generation.max_words must be positive, got %d", c.Generation.MaxWords)
+	check(c.Generation.MinLength >= 1 && c.Generation.MinLength <= c.Generation.MaxLength,
+		"generation.min_length must be in range [1, max_length], got %d", c.Generation.MinLength)
+	check(c.Generation.MaxRepeat >= 0, "generation.max_repeat can't be negative")
+	check(c.Generation.NoRepeatNgram >= 0, "generation.no_repeat_ngram can't be negative")
+	check(c.Generation.RepeatPenalty >= 0 && c.Generation.RepeatPenalty <= 1,
+		"generation.repeat_penalty must be in range [0, 1], got %g", c.Generation.RepeatPenalty)
+	check(c.Generation.Temperature > 0, "generation.temperature must be positive, got %g", c.Generation.Temperature)
+	check(oneOf(c.Generation.BlocklistMode, "off", "reject", "mask"),
+		"generation.blocklist_mode must be off, reject or mask, got %q", c.Generation.BlocklistMode)
+	_, err = logrus.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level: %v", err)
+	check(oneOf(c.Log.Format, "text", "json"), "log.format must be text or json, got %q", c.Log.Format)
+	check(oneOf(c.Log.Progress, "auto", "tty", "log", "none"),
+		"log.progress must be auto, tty, log or none, got %q", c.Log.Progress)
+	check(oneOf(c.Errors.Reporter, "auto", "errbit", "file", "none"),
+		"errors.reporter must be auto, errbit, file or none, got %q", c.Errors.Reporter)
+	check(c.Errors.Reporter != "errbit" || c.Errbit.configured(), "errbit.host, errbit.id and errbit.key are required by errbit reporter")
+	check(c.Errors.Reporter != "file" || c.Errors.File != "", "errors.file is required by file reporter")
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// oneOf reports whether v is one of vs.
+func oneOf(v string, vs ...string) bool {
+	for _, s := range vs {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package phrasegen
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want map[string]interface{}
+	}{
+		{"scalars", "a: 1\nb: text # comment\nc: \"quoted # not comment\"\nd: 'single'\n",
+			map[string]interface{}{"a": "1", "b": "text", "c": "quoted # not comment", "d": "single"}},
+		{"nesting", "corpus:\n  file: q.json\n  inner:\n    x: 2005\nlog:\n  level: debug\n",
+			map[string]interface{}{
+				"corpus": map[string]interface{}{"file": "q.json", "inner": map[string]interface{}{"x": "2005"}},
+				"log":    map[string]interface{}{"level": "debug"},
+			}},
+		{"inline list", "p: [dialog, \"a, b\", 'c']\n",
+			map[string]interface{}{"p": []string{"dialog", "a, b", "c"}}},
+		{"item list", "---\np:\n  - dialog\n  - \"pii\" # comment\nq: 2\n",
+			map[string]interface{}{"p": []string{"dialog", "pii"}, "q": "2"}},
+		{"empty", "p:\n", map[string]interface{}{"p": map[string]interface{}{}}},
+		{"escaped quote", `k: "say \"hi\" # there"`, map[string]interface{}{"k": `say "hi" # there`}},
+		{"flow mapping", "log: {level: debug, format: json}\nok: yes\n",
+			map[string]interface{}{"log": map[string]interface{}{"level": "debug", "format": "json"}, "ok": "true"}},
+		{"block scalar", "k: |\n  first\n  second\n", map[string]interface{}{"k": "first\nsecond\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, in := range []string{
+		"- item\n",
+		"just text\n",
+		"p:\n  a: 1\n  - item\n",
+		"p:\n  - [a, b]\n",
+	} {
+		if _, err := parseYAML(in); err == nil {
+			t.Errorf("parseYAML(%q) must fail", in)
+		}
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	in := `environment = "prod" # comment
+[corpus]
+file = 'q.json'
+pipeline = ["dialog", "pii"]
+from = 2005
+
+[generation.inner]
+x = 1.5
+
+[log]
+level = """
+debug"""
+errbit = {host = "h", id = 7}
+`
+	want := map[string]interface{}{
+		"environment": "prod",
+		"corpus":      map[string]interface{}{"file": "q.json", "pipeline": []string{"dialog", "pii"}, "from": "2005"},
+		"generation":  map[string]interface{}{"inner": map[string]interface{}{"x": "1.5"}},
+		"log":         map[string]interface{}{"level": "debug", "errbit": map[string]interface{}{"host": "h", "id": "7"}},
+	}
+	got, err := parseTOML(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTOML = %#v, want %#v", got, want)
+	}
+	for _, bad := range []string{"[corpus\n", "key value\n", "p = [[1, 2]]\n"} {
+		if _, err := parseTOML(bad); err == nil {
+			t.Errorf("parseTOML(%q) must fail", bad)
+		}
+	}
+}
+
+// loadConfig writes configuration file with the extension and loads it
+// over the default configuration.
+func loadConfig(t *testing.T, ext, data string) (Configuration, error) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config"+ext)
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := DefaultConfiguration()
+	err = c.LoadFile(path)
+	return c, err
+}
+
+func TestLoadFile(t *testing.T) {
+	files := map[string]string{
+		".yaml": `
+corpus:
+  file: quotes.json
+  from: 2005
+  to: 2005-06
+  pipeline: [dialog, normalize]
+  dedupe_threshold: 0.9
+chain:
+  order: 2
+generation:
+  stop_on_cycle: false
+  seed: 42
+errbit:
+  key: 12345
+`,
+		".toml": `
+[corpus]
+file = "quotes.json"
+from = 2005
+to = "2005-06"
+pipeline = ["dialog", "normalize"]
+dedupe_threshold = 0.9
+[chain]
+order = 2
+[generation]
+stop_on_cycle = false
+seed = 42
+[errbit]
+key = 12345
+`,
+		".json": `{"corpus": {"file": "quotes.json", "from": "2005", "to": "2005-06",
+			"pipeline": ["dialog", "normalize"], "dedupe_threshold": 0.9},
+			"chain": {"order": 2}, "generation": {"stop_on_cycle": false, "seed": 42},
+			"errbit": {"key": "12345"}}`,
+	}
+	for ext, data := range files {
+		t.Run(ext, func(t *testing.T) {
+			c, err := loadConfig(t, ext, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := DefaultConfiguration()
+			want.Corpus.File = "quotes.json"
+			want.Corpus.From = "2005"
+			want.Corpus.To = "2005-06"
+			want.Corpus.Pipeline = []string{"dialog", "normalize"}
+			want.Corpus.DedupeThreshold = 0.9
+			want.Chain.Order = 2
+			want.Generation.StopOnCycle = false
+			want.Generation.Seed = 42
+			want.Errbit.Key = "12345"
+			if !reflect.DeepEqual(c, want) {
+				t.Errorf("loaded %+v\nwant %+v", c, want)
+			}
+		})
+	}
+}
+
+func TestLoadFileTypeErrors(t *testing.T) {
+	tests := []struct {
+		ext, data, field string
+	}{
+		{".yaml", "chain:\n  order: two\n", "chain.order"},
+		{".yaml", "generation:\n  stop_on_cycle: maybe\n", "generation.stop_on_cycle"},
+		{".yaml", "corpus: quotes.json\n", "corpus"},
+		{".yaml", "corpus:\n  file: [a, b]\n", "corpus.file"},
+		{".yaml", "chain:\n  order:\n    x: 1\n", "chain.order"},
+		{".toml", "[generation]\ntemperature = hot\n", "generation.temperature"},
+		{".ini", "a=1", "unknown format"},
+	}
+	for _, tt := range tests {
+		_, err := loadConfig(t, tt.ext, tt.data)
+		if err == nil || !strings.Contains(err.Error(), tt.field) {
+			t.Errorf("load %q: error %v must mention %s", tt.data, err, tt.field)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Configuration)
+		want   string
+	}{
+		{"stage", func(c *Configuration) { c.Corpus.Pipeline = []string{"dialog", "pi"} }, `unknown stage "pi"`},
+		{"rating", func(c *Configuration) { c.Corpus.RatingWeight = "square" }, "corpus.rating_weight"},
+		{"from", func(c *Configuration) { c.Corpus.From = "yesterday" }, "corpus.from"},
+		{"to", func(c *Configuration) { c.Corpus.To = "2005-13" }, "corpus.to"},
+		{"from after to", func(c *Configuration) { c.Corpus.From, c.Corpus.To = "2006", "2005" }, "is after"},
+		{"numbers", func(c *Configuration) { c.Corpus.MinNumber, c.Corpus.MaxNumber = 10, 5 }, "min_number"},
+	}
+	for _, tt := range tests {
+		c := DefaultConfiguration()
+		tt.modify(&c)
+		err := c.Validate()
+		var verr ValidationError
+		if !errors.As(err, &verr) || len(verr) != 1 || !strings.Contains(verr[0], tt.want) {
+			t.Errorf("%s: Validate() = %v, want single error with %q", tt.name, err, tt.want)
+		}
+	}
+	c := DefaultConfiguration()
+	c.Corpus.From, c.Corpus.To = "2005", "2005"
+	if err := c.Validate(); err != nil {
+		t.Errorf("the same year in from and to: %v", err)
+	}
+}
+
+func TestMasked(t *testing.T) {
+	c := DefaultConfiguration()
+	c.Errbit.Key = "secret"
+	if m := c.Masked(); m.Errbit.Key != "***" || c.Errbit.Key != "secret" {
+		t.Errorf("Masked() key = %q, original %q", m.Errbit.Key, c.Errbit.Key)
+	}
+	b := &strings.Builder{}
+	if err := c.Print(b); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "secret") {
+		t.Errorf("Print() leaks errbit key: %s", b.String())
+	}
+	if m := DefaultConfiguration().Masked(); m.Errbit.Key != "" {
+		t.Errorf("Masked() of empty key = %q", m.Errbit.Key)
+	}
+}
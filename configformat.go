@@ -0,0 +1,99 @@
+package phrasegen
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// parseYAML decodes YAML document into tree of mappings, strings and lists
+// of strings applied to configuration by applyTree.
+func parseYAML(s string) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal([]byte(s), &doc); err != nil {
+		return nil, err
+	}
+	return normalizeTree(doc, "")
+}
+
+// parseTOML decodes TOML document into tree of mappings, strings and lists
+// of strings applied to configuration by applyTree.
+func parseTOML(s string) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if _, err := toml.Decode(s, &doc); err != nil {
+		return nil, err
+	}
+	return normalizeTree(doc, "")
+}
+
+// normalizeTree converts decoded document to the tree. Scalars are kept
+// as strings and converted to types of configuration fields later, so
+// values like 2005 stay intact in string fields. Key without value is an
+// empty mapping.
+func normalizeTree(doc map[string]interface{}, prefix string) (map[string]interface{}, error) {
+	tree := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		path := prefix + k
+		var err error
+		switch val := v.(type) {
+		case nil:
+			tree[k] = make(map[string]interface{})
+		case map[string]interface{}:
+			tree[k], err = normalizeTree(val, path+".")
+		case map[interface{}]interface{}:
+			m := make(map[string]interface{}, len(val))
+			for mk, mv := range val {
+				m[fmt.Sprint(mk)] = mv
+			}
+			tree[k], err = normalizeTree(m, path+".")
+		case []interface{}:
+			items := make([]string, 0, len(val))
+			for _, item := range val {
+				s, ok := scalarString(item)
+				if !ok {
+					return nil, fmt.Errorf("%s: only lists of scalars are supported", path)
+				}
+				items = append(items, s)
+			}
+			tree[k] = items
+		default:
+			s, ok := scalarString(val)
+			if !ok {
+				return nil, fmt.Errorf("%s: unsupported value %v", path, val)
+			}
+			tree[k] = s
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tree, nil
+}
+
+// scalarString formats decoded scalar the way it's written in the document.
+func scalarString(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case bool:
+		return strconv.FormatBool(val), true
+	case int:
+		return strconv.Itoa(val), true
+	case int64:
+		return strconv.FormatInt(val, 10), true
+	case uint64:
+		return strconv.FormatUint(val, 10), true
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64), true
+	case time.Time:
+		return val.Format(time.RFC3339), true
+	case fmt.Stringer:
+		// local dates and times of TOML
+		return val.String(), true
+	default:
+		return "", false
+	}
+}
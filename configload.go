@@ -0,0 +1,277 @@
+package phrasegen
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// LoadFile reads configuration file over current values. Format is chosen by
+// extension: .json, .yaml, .yml or .toml.
+func (c *Configuration) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, c)
+	case ".yaml", ".yml":
+		if tree, err = parseYAML(string(data)); err == nil {
+			err = c.applyTree(tree)
+		}
+	case ".toml":
+		if tree, err = parseTOML(string(data)); err == nil {
+			err = c.applyTree(tree)
+		}
+	default:
+		return fmt.Errorf("config %s: unknown format", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyTree sets fields by values of parsed YAML or TOML. Fields are matched
+// by json tags, scalars are converted to the field type. Unknown keys are
+// ignored the same way JSON does.
+func (c *Configuration) applyTree(tree map[string]interface{}) error {
+	return applyTree(reflect.ValueOf(c).Elem(), tree, "")
+}
+
+func applyTree(v reflect.Value, tree map[string]interface{}, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		value, ok := tree[name]
+		if !ok {
+			continue
+		}
+		path := prefix + name
+		fv := v.Field(i)
+		switch val := value.(type) {
+		case map[string]interface{}:
+			if f.Type.Kind() == reflect.Struct {
+				if err := applyTree(fv, val, path+"."); err != nil {
+					return err
+				}
+				continue
+			}
+			// key without value, e.g. empty list
+			if len(val) > 0 {
+				return fmt.Errorf("%s: expected %s, got mapping", path, f.Type)
+			}
+			if f.Type.Kind() == reflect.Slice {
+				fv.Set(reflect.MakeSlice(f.Type, 0, 0))
+			}
+		case []string:
+			if f.Type.Kind() != reflect.Slice || f.Type.Elem().Kind() != reflect.String {
+				return fmt.Errorf("%s: expected %s, got list", path, f.Type)
+			}
+			fv.Set(reflect.ValueOf(val))
+		case string:
+			if f.Type.Kind() == reflect.Struct {
+				return fmt.Errorf("%s: expected mapping, got %q", path, val)
+			}
+			if err := setField(fv, val); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyEnv overrides settings by environment variables named in env tags.
+func (c *Configuration) ApplyEnv(lookup func(key string) (string, bool)) error {
+	return walkFields(reflect.ValueOf(c).Elem(), func(v reflect.Value, f reflect.StructField) error {
+		name := f.Tag.Get("env")
+		if name == "" {
+			return nil
+		}
+		s, ok := lookup(name)
+		if !ok {
+			return nil
+		}
+		if err := setField(v, s); err != nil {
+			return fmt.Errorf("env %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// flagValue remembers the flag value until it's applied.
+type flagValue struct {
+	field reflect.Value
+	raw   *string
+}
+
+func (f flagValue) String() string {
+	if !f.field.IsValid() {
+		return ""
+	}
+	return formatField(f.field)
+}
+
+func (f flagValue) Set(s string) error {
+	if err := setField(reflect.New(f.field.Type()).Elem(), s); err != nil {
+		return err
+	}
+	*f.raw = s
+	return nil
+}
+
+// IsBoolFlag allows to set boolean flags without value.
+func (f flagValue) IsBoolFlag() bool {
+	return f.field.Kind() == reflect.Bool
+}
+
+// Flags holds flags registered by RegisterFlags.
+type Flags struct {
+	fs     *flag.FlagSet
+	values map[string]flagValue
+}
+
+// RegisterFlags registers flags for settings having flag tag. Current values
+// are used as defaults in usage. Flags are applied by Flags.Apply.
+func (c *Configuration) RegisterFlags(fs *flag.FlagSet) *Flags {
+	fl := &Flags{fs: fs, values: make(map[string]flagValue)}
+	_ = walkFields(reflect.ValueOf(c).Elem(), func(v reflect.Value, f reflect.StructField) error {
+		name := f.Tag.Get("flag")
+		if name == "" {
+			return nil
+		}
+		fv := flagValue{field: v, raw: new(string)}
+		fl.values[name] = fv
+		fs.Var(fv, name, f.Tag.Get("usage"))
+		return nil
+	})
+	return fl
+}
+
+// Apply sets values of flags given in command line to the configuration.
+func (fl *Flags) Apply(c *Configuration) error {
+	var err error
+	fl.fs.Visit(func(f *flag.Flag) {
+		fv, ok := fl.values[f.Name]
+		if !ok || err != nil {
+			return
+		}
+		err = walkFields(reflect.ValueOf(c).Elem(), func(v reflect.Value, sf reflect.StructField) error {
+			if sf.Tag.Get("flag") == f.Name {
+				return setField(v, *fv.raw)
+			}
+			return nil
+		})
+		if err != nil {
+			err = fmt.Errorf("flag %s: %w", f.Name, err)
+		}
+	})
+	return err
+}
+
+// Masked returns copy of configuration with secrets masked, so it may be
+// printed or logged.
+func (c Configuration) Masked() Configuration {
+	if c.Errbit.Key != "" {
+		c.Errbit.Key = "***"
+	}
+	return c
+}
+
+// Print writes configuration as indented JSON. Errbit key is masked.
+func (c *Configuration) Print(w io.Writer) error {
+	data, err := json.MarshalIndent(c.Masked(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// walkFields calls fn for each non-struct field of v recursively.
+func walkFields(v reflect.Value, fn func(v reflect.Value, f reflect.StructField) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Kind() == reflect.Struct {
+			if err := walkFields(v.Field(i), fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(v.Field(i), f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setField parses s according to the field type.
+func setField(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		parts := make([]string, 0)
+		for _, p := range strings.Split(s, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
+		}
+		v.Set(reflect.ValueOf(parts))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// formatField is reverse of setField.
+func formatField(v reflect.Value) string {
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = v.Index(i).String()
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
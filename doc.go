@@ -0,0 +1,56 @@
+// Package phrasegen generates new phrases by markov chains learned from texts.
+//
+// Generator is the entry point for embedding:
+//
+//	g, err := phrasegen.New(phrasegen.Options{
+//		Order:    2,
+//		Pipeline: []string{"dialog", "pii", "normalize", "dedupe"},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	src := &phrasegen.BashSource{Parser: utils.NewBashParser("quotes.json", logrus.InfoLevel)}
+//	if err := g.Train(ctx, src); err != nil {
+//		log.Println("some quotes are skipped:", err)
+//	}
+//	p, err := g.Generate(ctx, phrasegen.GenerateOptions{Candidates: 5, FillPlaceholders: true})
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(p.Text)
+//
+// Trained model is written by Generator.Save and restored by Generator.Load,
+// so training may be done once:
+//
+//	f, err := os.Create("model.json")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//	return g.Save(f)
+//
+// Texts may also come from memory by TextSource or from any other Source.
+//
+// Options.Backend chooses the model behind Generator, see BackendNames.
+// Generators backed by markov chain implement ChainProvider.
+//
+// Reply answers a message by a phrase starting with or containing its
+// keywords, which are words of the message weighted by TF-IDF against the
+// learned texts. Generators not implementing Replier return random phrases:
+//
+//	p, err := phrasegen.Reply(ctx, g, "what about the cat?", phrasegen.GenerateOptions{})
+//
+// GenerateWithTrace records each step of generation to find out why the
+// phrase came out as it is:
+//
+//	p, err := phrasegen.GenerateWithTrace(ctx, g, phrasegen.GenerateOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	for _, s := range p.Trace {
+//		fmt.Printf("%s -> %s: %.2f (%d alternatives)\n", s.State, s.Word, s.Probability, s.Alternatives)
+//	}
+//
+// App ties configuration of the command line application together with
+// logger, error reporter and trained generators.
+package phrasegen
@@ -0,0 +1,86 @@
+package phrasegen_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ferux/phraseGen"
+)
+
+// corpus is a tiny fixed corpus of the examples.
+var corpus = phrasegen.TextSource{
+	"The cat sleeps on the sofa.",
+	"The dog sleeps in the yard.",
+	"My cat likes fish.",
+	"The dog likes the cat.",
+}
+
+func ExampleNew() {
+	ctx := context.Background()
+	g, err := phrasegen.New(phrasegen.Options{Workers: 1})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := g.Train(ctx, corpus); err != nil {
+		fmt.Println(err)
+		return
+	}
+	p, err := g.Generate(ctx, phrasegen.GenerateOptions{Candidates: 3, Start: "my", Seed: 1})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(p.Text)
+	// Output:
+	// my cat likes fish.
+}
+
+func ExampleReply() {
+	ctx := context.Background()
+	g, err := phrasegen.New(phrasegen.Options{Workers: 1})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := g.Train(ctx, corpus); err != nil {
+		fmt.Println(err)
+		return
+	}
+	p, err := phrasegen.Reply(ctx, g, "What does your dog eat?", phrasegen.GenerateOptions{Candidates: 3, Seed: 1})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(p.Keywords)
+	fmt.Println(p.Text)
+	// Output:
+	// [dog]
+	// dog sleeps on the dog likes fish.
+}
+
+func ExampleGenerateWithTrace() {
+	ctx := context.Background()
+	g, err := phrasegen.New(phrasegen.Options{Workers: 1})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := g.Train(ctx, corpus); err != nil {
+		fmt.Println(err)
+		return
+	}
+	p, err := phrasegen.GenerateWithTrace(ctx, g, phrasegen.GenerateOptions{Start: "my", Seed: 1})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, s := range p.Trace {
+		fmt.Printf("%s -> %s: %d of %d, p=%.2f, log p=%.3f\n", s.State, s.Word, s.Count, s.Alternatives+1, s.Probability, s.LogProb)
+	}
+	// Output:
+	// my -> cat: 1 of 1, p=1.00, log p=0.000
+	// cat -> likes: 1 of 3, p=0.33, log p=-1.099
+	// likes -> fish: 1 of 2, p=0.50, log p=-1.792
+	// fish -> *END*: 1 of 1, p=1.00, log p=-1.792
+}
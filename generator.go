@@ -0,0 +1,194 @@
+package phrasegen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/ferux/phraseGen/markov"
+	"github.com/ferux/phraseGen/utils"
+)
+
+var (
+	// ErrNotTrained reports generator has nothing to generate from.
+	ErrNotTrained = errors.New("generator is not trained")
+	// ErrNoTrace reports generator can't record trace of generation.
+	ErrNoTrace = errors.New("generator can't trace phrases")
+)
+
+// Generator learns texts and generates new phrases. It's safe for
+// concurrent use.
+type Generator interface {
+	// Train learns texts of the source. It may be called several times,
+	// texts of every call are added to the model.
+	Train(ctx context.Context, src Source) error
+	// Generate returns a new phrase.
+	Generate(ctx context.Context, opts GenerateOptions) (Phrase, error)
+	// Save writes the model.
+	Save(w io.Writer) error
+	// Load replaces the model by the one written by Save.
+	Load(r io.Reader) error
+}
+
+// Options configures Generator created by New.
+type Options struct {
+	// Order is amount of previous words each word depends on, 1 if zero.
+	Order int
+	// KeepCase disables lowercasing of words.
+	KeepCase bool
+	// CharOrder is amount of previous characters each character depends on
+	// for char backend, 3 if zero.
+	CharOrder int
+	// Workers is amount of training goroutines, amount of CPUs if zero.
+	Workers int
+	// Pipeline lists preprocessing stages applied to texts before training,
+	// see utils.StageNames. Texts are learned as is if it's empty.
+	Pipeline []string
+	// Blocklist drops training texts with blocked words and rejects
+	// generated phrases containing them. Optional.
+	Blocklist *utils.Blocklist
+	// Prepare is called for each text after the pipeline. Returning
+	// false skips the text. Optional.
+	Prepare func(text string) (string, bool)
+	// Logger receives messages of the model, nil disables logging.
+	Logger markov.Logger
+	// OnPanic is deferred by training goroutines, e.g. ErrorReporter.ReportPanic.
+	// Optional.
+	OnPanic func()
+	// Backend is a name of the model, "markov" if empty. See BackendNames.
+	Backend string
+	// Templates are texts with slots for template backend, see ParseTemplate.
+	Templates []string
+}
+
+// GenerateOptions tunes generation of a phrase. Zero value is valid.
+type GenerateOptions struct {
+	// Candidates is amount of phrases generated to choose the best one from.
+	Candidates int
+	// Start is the first word of the phrase, optional.
+	Start string
+	// MinWords and MaxWords limit length of the phrase.
+	MinWords, MaxWords int
+	// MaxRepeat limits times the same word appears in the phrase, 0 is unlimited.
+	MaxRepeat int
+	// NoRepeatNgram bans repeating n-grams of given size, 0 disables.
+	NoRepeatNgram int
+	// RepeatPenalty multiplies chance of already used words, 0 disables.
+	RepeatPenalty float64
+	// StopOnCycle ends the phrase once it starts repeating itself.
+	StopOnCycle bool
+	// Temperature below 1 favours likely words, above 1 flattens chances.
+	// Zero keeps learned chances.
+	Temperature float64
+	// Seed makes generation reproducible, random if zero.
+	Seed int64
+	// Trace records steps of generation in Phrase.Trace, markov backend.
+	Trace bool
+	// Ranker chooses the best candidate, likelihood if nil.
+	Ranker markov.Ranker
+	// FillPlaceholders replaces placeholders like <NICK> with synthetic values.
+	FillPlaceholders bool
+	// Accept rejects candidate words by returning false. Optional.
+	Accept func(words []string) bool
+	// MinLength and MaxLength limit invented word in characters, char backend.
+	MinLength, MaxLength int
+	// RejectKnown rejects invented words which are learned ones, char backend.
+	RejectKnown bool
+	// Attempts limits tries to invent acceptable word, char backend, 1000 if
+	// zero. *markov.AttemptsError is returned when they are exhausted.
+	Attempts int
+}
+
+// Phrase is a generated phrase.
+type Phrase struct {
+	Text  string
+	Words []string
+	Score float64
+	// Keywords of the replied message the phrase is built around.
+	Keywords []string
+	// Trace holds steps of generation if GenerateOptions.Trace is set.
+	Trace []markov.Step
+}
+
+// ChainProvider is implemented by generators backed by markov chain.
+type ChainProvider interface {
+	// Chain returns the chain. It mustn't be changed.
+	Chain() *markov.Chain
+}
+
+// GenerateWithTrace generates phrase recording state, chosen word, its
+// count and probability, amount of alternatives and cumulative
+// log-probability of each step in Phrase.Trace. Only generators backed by
+// markov chain record traces.
+func GenerateWithTrace(ctx context.Context, g Generator, opts GenerateOptions) (Phrase, error) {
+	if _, ok := g.(ChainProvider); !ok {
+		return Phrase{}, ErrNoTrace
+	}
+	opts.Trace = true
+	return g.Generate(ctx, opts)
+}
+
+// Replier is implemented by generators able to answer a message.
+type Replier interface {
+	// Reply generates phrase related to the message.
+	Reply(ctx context.Context, message string, opts GenerateOptions) (Phrase, error)
+}
+
+// Reply generates phrase answering the message if generator is Replier
+// and a random phrase otherwise.
+func Reply(ctx context.Context, g Generator, message string, opts GenerateOptions) (Phrase, error) {
+	if r, ok := g.(Replier); ok {
+		return r.Reply(ctx, message, opts)
+	}
+	return g.Generate(ctx, opts)
+}
+
+// Backend creates generator of the options.
+type Backend func(opts Options) (Generator, error)
+
+// DefaultBackend is used if Options.Backend is empty.
+const DefaultBackend = "markov"
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Backend{
+		DefaultBackend: newChainGenerator,
+	}
+)
+
+// RegisterBackend makes backend available by name.
+func RegisterBackend(name string, b Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	backends[name] = b
+}
+
+// BackendNames returns sorted names of available backends.
+func BackendNames() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates generator of the backend set by options.
+func New(opts Options) (Generator, error) {
+	name := opts.Backend
+	if name == "" {
+		name = DefaultBackend
+	}
+	backendsMu.RLock()
+	b, ok := backends[name]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+	return b(opts)
+}
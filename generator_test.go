@@ -0,0 +1,155 @@
+package phrasegen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// stubGenerator returns its text as the only phrase.
+type stubGenerator struct {
+	text string
+}
+
+func (g *stubGenerator) Train(ctx context.Context, src Source) error { return nil }
+
+func (g *stubGenerator) Generate(ctx context.Context, opts GenerateOptions) (Phrase, error) {
+	return Phrase{Text: g.text}, nil
+}
+
+func (g *stubGenerator) Save(w io.Writer) error { return nil }
+func (g *stubGenerator) Load(r io.Reader) error { return nil }
+
+func TestRegisterBackend(t *testing.T) {
+	RegisterBackend("stub", func(opts Options) (Generator, error) {
+		return &stubGenerator{text: opts.Templates[0]}, nil
+	})
+	defer func() {
+		backendsMu.Lock()
+		delete(backends, "stub")
+		backendsMu.Unlock()
+	}()
+	want := []string{"char", "markov", "stub", "template"}
+	if got := BackendNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("BackendNames() = %v, want %v", got, want)
+	}
+	g, err := New(Options{Backend: "stub", Templates: []string{"hi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if p, _ := g.Generate(ctx, GenerateOptions{}); p.Text != "hi" {
+		t.Errorf("Generate() = %q, want hi", p.Text)
+	}
+	// stub isn't a Replier nor a ChainProvider
+	if p, _ := Reply(ctx, g, "message", GenerateOptions{}); p.Text != "hi" {
+		t.Errorf("Reply() = %q, want hi", p.Text)
+	}
+	if _, err := GenerateWithTrace(ctx, g, GenerateOptions{}); err != ErrNoTrace {
+		t.Errorf("GenerateWithTrace() error = %v, want ErrNoTrace", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	g, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.(*chainGenerator); !ok {
+		t.Errorf("default backend is %T, want markov", g)
+	}
+	if _, err := New(Options{Backend: "unknown"}); err == nil {
+		t.Error("unknown backend must fail")
+	}
+	if _, err := New(Options{Pipeline: []string{"unknown"}}); err == nil {
+		t.Error("unknown pipeline stage must fail")
+	}
+	if _, err := New(Options{Backend: "template"}); err == nil {
+		t.Error("template backend without templates must fail")
+	}
+	if _, err := g.Generate(context.Background(), GenerateOptions{}); !errors.Is(err, ErrNotTrained) {
+		t.Errorf("Generate() of untrained generator error = %v, want ErrNotTrained", err)
+	}
+}
+
+func TestGeneratorSaveLoad(t *testing.T) {
+	ctx := context.Background()
+	src := TextSource{"The cat sleeps on the sofa.", "The dog sleeps in the yard."}
+	g, err := New(Options{Order: 2, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Train(ctx, src); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := g.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	c := loaded.(ChainProvider).Chain()
+	if c.Order() != 2 || c.Documents() != 2 {
+		t.Errorf("loaded chain of order %d and %d documents, want 2 and 2", c.Order(), c.Documents())
+	}
+	opts := GenerateOptions{Candidates: 3, Seed: 7}
+	want, err := g.Generate(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := loaded.Generate(ctx, opts); err != nil || got.Text != want.Text {
+		t.Errorf("loaded generator generated %q, %v, want %q", got.Text, err, want.Text)
+	}
+	if err := loaded.Load(bytes.NewBufferString("{}")); err == nil {
+		t.Error("chain without version must fail to load")
+	}
+}
+
+func TestChainGeneratorTrainKeepsChain(t *testing.T) {
+	ctx := context.Background()
+	g, err := New(Options{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Train(ctx, TextSource{"The cat sleeps on the sofa."}); err != nil {
+		t.Fatal(err)
+	}
+	before := g.(ChainProvider).Chain()
+	records := before.GetTotalRecords()
+
+	// readers of the chain run alongside training
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_, _ = Reply(ctx, g, "the cat", GenerateOptions{})
+				g.(ChainProvider).Chain().Stats(3)
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		if err := g.Train(ctx, TextSource{"The dog sleeps in the yard.", "My cat likes fish."}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	after := g.(ChainProvider).Chain()
+	if before.GetTotalRecords() != records || after == before {
+		t.Errorf("training changed the chain in use: %d records, want %d", before.GetTotalRecords(), records)
+	}
+	if after.Documents() != 11 {
+		t.Errorf("trained chain has %d documents, want 11", after.Documents())
+	}
+}
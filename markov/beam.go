@@ -0,0 +1,113 @@
+package markov
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// beam is a partial sentence kept during beam search.
+type beam struct {
+	words []string
+	state string
+	logp  float64
+}
+
+// BeamSearch returns up to k most probable complete sentences starting from
+// the start word with at most maxLen words. Empty start or *START* means
+// beginning of the sentence. Start word which doesn't begin a sentence
+// continues every core ending with it. Score of each candidate is its
+// log-probability given the start word. Result is deterministic and sorted
+// by score.
+func (c *Chain) BeamSearch(start string, k, maxLen int) ([]Candidate, error) {
+	if k < 1 {
+		k = 1
+	}
+	if maxLen < 1 {
+		maxLen = maxWords
+	}
+	start = strings.TrimSpace(start)
+	beams := []beam{{state: "*START*"}}
+	if start != "" && start != "*START*" {
+		start = c.normalize(start)
+		cores, err := c.startCores(start)
+		if err != nil {
+			return nil, err
+		}
+		beams = beams[:0]
+		for _, core := range cores {
+			beams = append(beams, beam{words: []string{start}, state: core})
+		}
+	} else if _, err := c.GetCells("*START*"); err != nil {
+		return nil, err
+	}
+
+	done := make([]Candidate, 0, k)
+	// index of each complete sentence in done, different cores may
+	// produce the same words
+	found := make(map[string]int)
+	for len(beams) > 0 {
+		next := make([]beam, 0, len(beams)*2)
+		for _, b := range beams {
+			for _, cell := range c.d[b.state] {
+				if cell.chance <= 0 {
+					continue
+				}
+				logp := b.logp + math.Log(cell.chance/100)
+				if cell.ctype == End {
+					text := strings.Join(b.words, " ")
+					if i, ok := found[text]; !ok {
+						found[text] = len(done)
+						done = append(done, Candidate{Words: b.words, Score: logp})
+					} else if logp > done[i].Score {
+						done[i].Score = logp
+					}
+					continue
+				}
+				if len(b.words) >= maxLen {
+					continue
+				}
+				words := append(append(make([]string, 0, len(b.words)+1), b.words...), cell.word)
+				next = append(next, beam{words: words, state: c.nextCore(b.state, cell.word), logp: logp})
+			}
+		}
+		sort.SliceStable(next, func(i, j int) bool {
+			if next[i].logp != next[j].logp {
+				return next[i].logp > next[j].logp
+			}
+			return strings.Join(next[i].words, " ") < strings.Join(next[j].words, " ")
+		})
+		if len(next) > k {
+			next = next[:k]
+		}
+		// no partial sentence can beat the worst of k complete ones anymore
+		if len(done) >= k {
+			sortCandidates(done)
+			done = done[:k]
+			for text := range found {
+				delete(found, text)
+			}
+			for i, cand := range done {
+				found[strings.Join(cand.Words, " ")] = i
+			}
+			if len(next) == 0 || next[0].logp <= done[k-1].Score {
+				break
+			}
+		}
+		beams = next
+	}
+	sortCandidates(done)
+	if len(done) > k {
+		done = done[:k]
+	}
+	return done, nil
+}
+
+func sortCandidates(cs []Candidate) {
+	sort.SliceStable(cs, func(i, j int) bool {
+		if cs[i].Score != cs[j].Score {
+			return cs[i].Score > cs[j].Score
+		}
+		return cs[i].String() < cs[j].String()
+	})
+}
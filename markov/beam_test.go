@@ -0,0 +1,115 @@
+package markov
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func beamChain(t *testing.T) *Chain {
+	t.Helper()
+	c := NewChain()
+	for _, s := range []string{"the cat sleeps.", "the cat sleeps.", "the cat sleeps.", "the dog runs."} {
+		if err := c.ParseText(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.CalculateCells()
+	return c
+}
+
+func TestBeamSearch(t *testing.T) {
+	c := beamChain(t)
+	tests := []struct {
+		name   string
+		start  string
+		k      int
+		maxLen int
+		want   [][]string
+		scores []float64
+	}{
+		{"best", "", 1, 10, [][]string{{"the", "cat", "sleeps"}}, []float64{math.Log(0.75)}},
+		{"all", "*START*", 5, 10, [][]string{{"the", "cat", "sleeps"}, {"the", "dog", "runs"}}, []float64{math.Log(0.75), math.Log(0.25)}},
+		{"start word", "Dog", 3, 10, [][]string{{"dog", "runs"}}, []float64{0}},
+		{"too short", "", 3, 2, [][]string{}, []float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.BeamSearch(tt.start, tt.k, tt.maxLen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			words := make([][]string, 0, len(got))
+			for i, cand := range got {
+				words = append(words, cand.Words)
+				if math.Abs(cand.Score-tt.scores[i]) > 1e-9 {
+					t.Errorf("score of %v = %g, want %g", cand.Words, cand.Score, tt.scores[i])
+				}
+			}
+			if !reflect.DeepEqual(words, tt.want) {
+				t.Errorf("BeamSearch(%q, %d, %d) = %v, want %v", tt.start, tt.k, tt.maxLen, words, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeamSearchDeterministic(t *testing.T) {
+	c := NewChain()
+	for _, s := range []string{"a b.", "a c.", "a d."} {
+		if err := c.ParseText(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.CalculateCells()
+	first, err := c.BeamSearch("", 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 2 || first[0].String() != "a b." || first[1].String() != "a c." {
+		t.Fatalf("ties must be broken by text, got %v", first)
+	}
+	for i := 0; i < 10; i++ {
+		got, _ := c.BeamSearch("", 2, 5)
+		if !reflect.DeepEqual(got, first) {
+			t.Fatalf("run %d = %v, want %v", i, got, first)
+		}
+	}
+}
+
+func TestBeamSearchUnknownStart(t *testing.T) {
+	if _, err := beamChain(t).BeamSearch("bird", 1, 5); err == nil {
+		t.Fatal("unknown start word must fail")
+	}
+}
+
+func TestBeamSearchOrder(t *testing.T) {
+	c := NewChainOrder(2)
+	for _, s := range []string{"the cat sleeps.", "my cat runs.", "my cat runs.", "a dog runs.", "the dog runs."} {
+		if err := c.ParseText(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.CalculateCells()
+	tests := []struct {
+		start string
+		want  []string
+	}{
+		{"the", []string{"the cat sleeps.", "the dog runs."}},
+		// cat and dog don't begin sentences, every core ending with them is continued
+		{"cat", []string{"cat runs.", "cat sleeps."}},
+		{"dog", []string{"dog runs."}},
+	}
+	for _, tt := range tests {
+		got, err := c.BeamSearch(tt.start, 5, 10)
+		if err != nil {
+			t.Fatalf("BeamSearch(%q) failed: %v", tt.start, err)
+		}
+		texts := make([]string, 0, len(got))
+		for _, cand := range got {
+			texts = append(texts, cand.String())
+		}
+		if !reflect.DeepEqual(texts, tt.want) {
+			t.Errorf("BeamSearch(%q) = %q, want %q", tt.start, texts, tt.want)
+		}
+	}
+}
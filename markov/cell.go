@@ -6,11 +6,19 @@ type Cell struct {
 	count  uint64
 	ctype  CellType
 	chance float64
+	// weight is a sum of weights of each addition of the cell.
+	// It equals to count unless cell was added with custom weight.
+	weight float64
 }
 
 // NewCell creates new cell
 func NewCell(w string, c uint64, t CellType) Cell {
-	return Cell{w, c, t, 0}
+	return Cell{w, c, t, 0, float64(c)}
+}
+
+// NewWeightedCell creates new cell added once with given weight
+func NewWeightedCell(w string, weight float64, t CellType) Cell {
+	return Cell{w, 1, t, 0, weight}
 }
 
 // GetWord returns word
@@ -34,7 +42,7 @@ func (c *Cell) Valid() bool {
 	if c.word == "*START*" && c.ctype != Start {
 		return false
 	}
-	if c.count < 1 {
+	if c.count < 1 || c.weight <= 0 {
 		return false
 	}
 
@@ -48,3 +56,27 @@ func (c *Cell) ApplyChance(total uint64) {
 	}
 	c.chance = (float64(c.count) / float64(total)) * 100.0
 }
+
+// ApplyWeightedChance calculates chance of appearing current cell in chain
+// using its weight
+func (c *Cell) ApplyWeightedChance(total float64) {
+	if total < c.weight || total <= 0 {
+		return
+	}
+	c.chance = (c.weight / total) * 100.0
+}
+
+// GetCount returns how many times the cell was added
+func (c *Cell) GetCount() uint64 {
+	return c.count
+}
+
+// GetChance returns chance of appearing the cell in percents
+func (c *Cell) GetChance() float64 {
+	return c.chance
+}
+
+// GetWeight returns total weight of the cell
+func (c *Cell) GetWeight() float64 {
+	return c.weight
+}
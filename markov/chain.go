@@ -7,62 +7,114 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"os"
 	"regexp"
 	"strings"
 	"time"
-
-	"github.com/sirupsen/logrus"
 )
 
 var (
 	// ErrNotFound reports row was not found
 	ErrNotFound = errors.New("not found")
-	// l logger for logging package messages
-	l = logrus.New().WithField("pkg", "markov")
+	// ErrInvalidCell reports cell can't be added to the chain
+	ErrInvalidCell = errors.New("invalid cell")
+	// ErrDeadEnd reports there's no way to continue the chain
+	ErrDeadEnd = errors.New("dead end")
+	// ErrEmptyChain reports chain has no records
+	ErrEmptyChain = errors.New("chain is empty")
+	// ErrRejected reports generated phrase was rejected
+	ErrRejected = errors.New("phrase rejected")
+	// ErrEmptyText reports there's nothing to parse
+	ErrEmptyText = errors.New("text is empty")
+	// ErrIncompatible reports chains can't be merged
+	ErrIncompatible = errors.New("incompatible chains")
 
 	// checkSymbols regexp for finding symbols only
 	// checkSymbolsRegex = regexp.MustCompile(`^[\W|\D]$`)
 
 	// addSpace regexp searches throught words and marks symbols attached to these words.
-	addSpaceRegex = regexp.MustCompile(`(?m)([а-яА-Я\w\-]+)([.,;:!?\(\)\"\'])`)
-
+	addSpaceRegex = regexp.MustCompile(`(?m)([а-яА-Я\w\-<>]+)([.,;:!?\(\)\"\'])`)
 )
 
 // Chain contains dictionary of parsed text
 type Chain struct {
 	d            map[string][]Cell
 	totalRecords uint64
+
+	// order is amount of previous words forming the core
+	order int
+	// keepCase disables lowercasing of words
+	keepCase bool
+
+	// docs is amount of parsed texts, df is amount of texts containing
+	// each lowercased word
+	docs uint64
+	df   map[string]uint64
+
+	log Logger
 }
 
 // NewChain creates new chain
 // nolint
 func NewChain() *Chain {
-	l.Info("Created new Chain")
-	return &Chain{make(map[string][]Cell), 0}
+	return NewChainOrder(1)
+}
+
+// NewChainOrder creates new chain where each word depends on order previous words.
+func NewChainOrder(order int) *Chain {
+	if order < 1 {
+		order = 1
+	}
+	return &Chain{d: make(map[string][]Cell), df: make(map[string]uint64), order: order, log: nopLogger{}}
+}
+
+// Order returns amount of previous words forming the core.
+func (c *Chain) Order() int {
+	return c.order
+}
+
+// KeepCase reports whether parsed words keep their case.
+func (c *Chain) KeepCase() bool {
+	return c.keepCase
+}
+
+// SetKeepCase disables lowercasing of parsed words.
+func (c *Chain) SetKeepCase(keep bool) {
+	c.keepCase = keep
+}
+
+// SetLogger sets logger for chain messages. Nil disables logging.
+func (c *Chain) SetLogger(log Logger) {
+	if log == nil {
+		log = nopLogger{}
+	}
+	c.log = log
 }
 
 // AddCell adds new cell to dictionary. If there's no  records of the core string
 // new row will be created.
-func (c *Chain) AddCell(core string, cell Cell) {
+func (c *Chain) AddCell(core string, cell Cell) error {
 	if !cell.Valid() {
-		fmt.Println("Cell is not valid. Skipping.")
-		return
+		return fmt.Errorf("%w: %q after %q", ErrInvalidCell, cell.word, core)
 	}
 	c.totalRecords++
 	_, ok := c.d[core]
 	if !ok {
 		c.d[core] = make([]Cell, 1)
 		c.d[core][0] = cell
-		return
+		return nil
 	}
 	for i := range c.d[core] {
 		if c.d[core][i].word == cell.word {
-			c.d[core][i].count++
-			return
+			c.d[core][i].count += cell.count
+			c.d[core][i].weight += cell.weight
+			return nil
 		}
 	}
 	c.d[core] = append(c.d[core], cell)
+	return nil
 }
 
 // GetCells gets cell slice of core
@@ -70,28 +122,57 @@ func (c *Chain) GetCells(core string) ([]Cell, error) {
 	if ca, ok := c.d[core]; ok {
 		return ca, nil
 	}
-	fmt.Printf("Core: %s\tNot found\n", core)
-	return nil, ErrNotFound
+	return nil, fmt.Errorf("core %q: %w", core, ErrNotFound)
 }
 
 // GetNextWord for generating
 func (c *Chain) GetNextWord(core string) (Cell, error) {
 	rand.Seed(time.Now().UnixNano())
+	return c.pickCell(core, rand.Float64()*100)
+}
+
+// nextCell picks next cell using provided random source.
+func (c *Chain) nextCell(core string, rnd *rand.Rand) (Cell, error) {
+	return c.pickCell(core, rnd.Float64()*100)
+}
+
+// pickCell returns cell of core which covers the pick value.
+func (c *Chain) pickCell(core string, pick float64) (Cell, error) {
+	if len(c.d) == 0 {
+		return Cell{}, ErrEmptyChain
+	}
 	cells, err := c.GetCells(core)
 	if err != nil {
-		return Cell{}, err
+		c.log.Debugf("dead end at %q", core)
+		return Cell{}, fmt.Errorf("%w: %w", ErrDeadEnd, err)
 	}
-	pick := rand.Float64() * 100
 	value := 0.00
-
-	for _, c := range cells {
+	last := -1
+	for i, c := range cells {
 		value += c.chance
 		// fmt.Printf("Rolling dice: %6.2f%%\tChance: %6.2f%%\n", pick, value)
 		if pick < value {
 			return c, nil
 		}
+		if c.chance > 0 {
+			last = i
+		}
 	}
-	return Cell{}, errors.New("something went wrong")
+	// chances may sum up to a bit less than 100 because of rounding
+	if last >= 0 {
+		return cells[last], nil
+	}
+	return Cell{}, fmt.Errorf("%w: chances of %q are not calculated", ErrDeadEnd, core)
+}
+
+// chanceOf returns chance of word following the core.
+func (c *Chain) chanceOf(core, word string) (float64, bool) {
+	for _, cell := range c.d[core] {
+		if cell.word == word {
+			return cell.chance, true
+		}
+	}
+	return 0, false
 }
 
 // GetTotalRecords returns total amount of records
@@ -102,12 +183,12 @@ func (c *Chain) GetTotalRecords() uint64 {
 // CalculateCells sets chance of appearance of each cell.
 func (c *Chain) CalculateCells() {
 	for k := range c.d {
-		var total uint64
+		var total float64
 		for _, vc := range c.d[k] {
-			total += vc.count
+			total += vc.weight
 		}
 		for ck := range c.d[k] {
-			c.d[k][ck].ApplyChance(total)
+			c.d[k][ck].ApplyWeightedChance(total)
 		}
 	}
 }
@@ -127,36 +208,60 @@ func (c *Chain) Beautify(data []byte, err error) ([]byte, error) {
 	return buf.Bytes(), err
 }
 
-// Iterate throught dictionary.
+// Iterate throught dictionary printing it to stdout.
+//
+// Deprecated: use Dump.
 func (c *Chain) Iterate() {
-	for k, v := range c.d {
-		fmt.Printf("Word: %s [\n", k)
+	_ = c.Dump(os.Stdout)
+}
+
+// Dump writes every core and its cells to w in alphabetical order of cores.
+func (c *Chain) Dump(w io.Writer) error {
+	var err error
+	c.Walk(func(k string, v []Cell) bool {
+		if _, err = fmt.Fprintf(w, "Word: %s [\n", k); err != nil {
+			return false
+		}
 		for _, cell := range v {
-			fmt.Printf("\tWord: %20s\tCount: %d\tChance: %6.2f%%\n", cell.word, cell.count, cell.chance)
+			if _, err = fmt.Fprintf(w, "\tWord: %20s\tCount: %d\tChance: %6.2f%%\n", cell.word, cell.count, cell.chance); err != nil {
+				return false
+			}
 		}
-		fmt.Println("]")
-	}
+		_, err = fmt.Fprintln(w, "]")
+		return err == nil
+	})
+	return err
 }
 
 // Reset erases all rows from dictionary.
 func (c *Chain) Reset() {
 	c.d = make(map[string][]Cell)
 	c.totalRecords = 0
+	c.docs = 0
+	c.df = make(map[string]uint64)
 }
 
 // ParseText parses the text
 func (c *Chain) ParseText(s string) error {
-	if len(s) == 0 {
-		return errors.New("string is empty")
+	return c.ParseTextWeighted(s, 1)
+}
+
+// ParseTextWeighted parses the text adding each transition with given weight
+func (c *Chain) ParseTextWeighted(s string, weight float64) error {
+	if weight <= 0 {
+		return fmt.Errorf("%w: weight %g", ErrInvalidCell, weight)
 	}
-	
 	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return ErrEmptyText
+	}
 	if s[len(s)-1] != '.' {
 		s = s + "."
 	}
 	s = addSpace(s)
 
 	words := strings.Split(s, " ")
+	seen := make(map[string]struct{})
 	prevCore := "*START*"
 	for _, w := range words {
 		w = strings.TrimSpace(w)
@@ -165,25 +270,74 @@ func (c *Chain) ParseText(s string) error {
 		}
 		switch {
 		case w == ".":
-			c.AddCell(prevCore, NewCell("*END*", 1, End))
+			c.addCell(prevCore, NewWeightedCell("*END*", weight, End))
 			prevCore = "*START*"
 		case w[len(w)-1] == 46:
-			c.AddCell(prevCore, NewCell(w[:len(w)-1], 1, Word))
-			c.AddCell(w[:len(w)-1], NewCell("*END*", 1, End))
+			wl := c.normalize(w[:len(w)-1])
+			seen[strings.ToLower(wl)] = struct{}{}
+			c.addCell(prevCore, NewWeightedCell(wl, weight, Word))
+			c.addCell(c.nextCore(prevCore, wl), NewWeightedCell("*END*", weight, End))
 			prevCore = "*START*"
+		case isPlaceholder(w):
+			c.addCell(prevCore, NewWeightedCell(w, weight, Word))
+			prevCore = c.nextCore(prevCore, w)
 		case w[len(w)-1] > 32 && w[len(w)-1] < 65:
-			
+
 			continue
 		default:
-			wl := strings.ToLower(w)
-			cell := NewCell(wl, 1, Word)
-			c.AddCell(prevCore, cell)
-			prevCore = wl
+			wl := c.normalize(w)
+			seen[strings.ToLower(wl)] = struct{}{}
+			cell := NewWeightedCell(wl, weight, Word)
+			c.addCell(prevCore, cell)
+			prevCore = c.nextCore(prevCore, wl)
 		}
 	}
+	c.countDocument(seen)
 	return nil
 }
 
+// normalize lowercases the word unless chain keeps case.
+func (c *Chain) normalize(w string) string {
+	if c.keepCase || isPlaceholder(w) {
+		return w
+	}
+	return strings.ToLower(w)
+}
+
+// nextCore returns core following the core after the word. Core consists of
+// up to order last words, the beginning of sentence is marked with *START*.
+func (c *Chain) nextCore(core, word string) string {
+	if c.order <= 1 {
+		return word
+	}
+	parts := append(strings.Split(core, " "), word)
+	if len(parts) > c.order {
+		parts = parts[len(parts)-c.order:]
+	}
+	return strings.Join(parts, " ")
+}
+
+// addCell adds cell skipping invalid ones.
+func (c *Chain) addCell(core string, cell Cell) {
+	if err := c.AddCell(core, cell); err != nil {
+		c.log.Warnf("skipping cell: %v", err)
+	}
+}
+
+// isPlaceholder reports whether w is a placeholder like <URL>
+// which replaced personal data in the text.
+func isPlaceholder(w string) bool {
+	if len(w) < 3 || w[0] != '<' || w[len(w)-1] != '>' {
+		return false
+	}
+	for _, r := range w[1 : len(w)-1] {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
 func addSpace(s string) string {
 	return addSpaceRegex.ReplaceAllString(s, "$1 $2")
 }
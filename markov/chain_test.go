@@ -0,0 +1,100 @@
+package markov
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestAddSpace(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"привет, мир.", "привет , мир ."},
+		{"<NICK>: привет", "<NICK> : привет"},
+		{"<NICK> привет", "<NICK> привет"},
+		{"<NICK>привет", "<NICK>привет"},
+	}
+	for _, tt := range tests {
+		if got := addSpace(tt.in); got != tt.want {
+			t.Errorf("addSpace(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseTextPlaceholders(t *testing.T) {
+	c := NewChain()
+	if err := c.ParseText("<NICK>: привет, <NICK> пока."); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"*START*": {"<NICK>"},
+		"<NICK>":  {"привет", "пока"},
+		"привет":  {"<NICK>"},
+		"пока":    {"*END*"},
+	}
+	got := make(map[string][]string)
+	c.Walk(func(core string, cells []Cell) bool {
+		for _, cell := range cells {
+			got[core] = append(got[core], cell.GetWord())
+		}
+		return true
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cells = %v, want %v", got, want)
+	}
+}
+
+// recordLogger keeps formatted messages.
+type recordLogger struct {
+	messages []string
+}
+
+func (l *recordLogger) Debugf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *recordLogger) Infof(format string, args ...interface{}) {
+	l.Debugf(format, args...)
+}
+
+func (l *recordLogger) Warnf(format string, args ...interface{}) {
+	l.Debugf(format, args...)
+}
+
+func TestChainErrors(t *testing.T) {
+	c := NewChain()
+	if _, err := c.GetNextWord("*START*"); !errors.Is(err, ErrEmptyChain) {
+		t.Errorf("GetNextWord() of empty chain error = %v, want ErrEmptyChain", err)
+	}
+	for _, cell := range []Cell{NewCell("", 1, Word), NewCell("*END*", 1, Word), NewCell("*START*", 1, End)} {
+		if err := c.AddCell("*START*", cell); !errors.Is(err, ErrInvalidCell) {
+			t.Errorf("AddCell(%+v) error = %v, want ErrInvalidCell", cell, err)
+		}
+	}
+	if c.GetTotalRecords() != 0 {
+		t.Errorf("invalid cells were counted: %d records", c.GetTotalRecords())
+	}
+
+	if err := c.AddCell("*START*", NewCell("кот", 1, Word)); err != nil {
+		t.Fatal(err)
+	}
+	c.CalculateCells()
+	if cell, err := c.GetNextWord("*START*"); err != nil || cell.GetWord() != "кот" {
+		t.Errorf("GetNextWord() = %v, %v, want кот", cell, err)
+	}
+	l := &recordLogger{}
+	c.SetLogger(l)
+	_, err := c.GetNextWord("кот")
+	if !errors.Is(err, ErrDeadEnd) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetNextWord() of unknown core error = %v, want ErrDeadEnd and ErrNotFound", err)
+	}
+	if want := []string{`dead end at "кот"`}; !reflect.DeepEqual(l.messages, want) {
+		t.Errorf("logged %q, want %q", l.messages, want)
+	}
+	c.SetLogger(nil)
+	if _, err := c.GetNextWord("кот"); !errors.Is(err, ErrDeadEnd) {
+		t.Errorf("GetNextWord() without logger error = %v, want ErrDeadEnd", err)
+	}
+}
@@ -0,0 +1,193 @@
+package markov
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"unicode"
+)
+
+// CharChain is a markov chain of characters. It invents words and nicknames
+// similar to the learned ones. Start and end of a word are Start and End cells.
+type CharChain struct {
+	c     *Chain
+	order int
+	// vocab holds lowercased learned words
+	vocab map[string]struct{}
+}
+
+// CharOptions tunes word generation.
+type CharOptions struct {
+	// MinLength of the word in characters, 1 if zero.
+	MinLength int
+	// MaxLength of the word in characters, 20 if zero.
+	MaxLength int
+	// RejectKnown rejects learned words.
+	RejectKnown bool
+	// Attempts to generate acceptable word, 100 if zero.
+	Attempts int
+}
+
+// AttemptsError reports that no acceptable word was invented in given
+// amount of attempts. It matches ErrRejected.
+type AttemptsError struct {
+	Attempts int
+}
+
+func (e *AttemptsError) Error() string {
+	return fmt.Sprintf("%v in %d attempts", ErrRejected, e.Attempts)
+}
+
+// Unwrap returns ErrRejected.
+func (e *AttemptsError) Unwrap() error {
+	return ErrRejected
+}
+
+// NewCharChain creates chain where each character depends on order
+// previous ones.
+func NewCharChain(order int) *CharChain {
+	if order < 1 {
+		order = 1
+	}
+	return &CharChain{c: NewChainOrder(1), order: order, vocab: make(map[string]struct{})}
+}
+
+// Order returns amount of previous characters forming the core.
+func (cc *CharChain) Order() int {
+	return cc.order
+}
+
+// Len returns amount of learned distinct words.
+func (cc *CharChain) Len() int {
+	return len(cc.vocab)
+}
+
+// AddWord learns the word. Chances must be recalculated afterwards.
+func (cc *CharChain) AddWord(word string, weight float64) error {
+	runes := []rune(strings.TrimSpace(word))
+	if len(runes) == 0 {
+		return ErrEmptyText
+	}
+	for i, r := range runes {
+		if err := cc.c.AddCell(cc.state(runes[:i]), NewWeightedCell(string(r), weight, Word)); err != nil {
+			return err
+		}
+	}
+	if err := cc.c.AddCell(cc.state(runes), NewWeightedCell("*END*", weight, End)); err != nil {
+		return err
+	}
+	cc.vocab[strings.ToLower(string(runes))] = struct{}{}
+	return nil
+}
+
+// AddText learns words of the text, repeated words weigh more.
+func (cc *CharChain) AddText(text string, weight float64) error {
+	for _, w := range tokenize(text) {
+		if !isVocabWord(w) {
+			continue
+		}
+		if err := cc.AddWord(w, weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddVocabulary learns distinct words of the word chain.
+func (cc *CharChain) AddVocabulary(c *Chain) error {
+	var err error
+	c.Walk(func(core string, cells []Cell) bool {
+		for _, cell := range cells {
+			if cell.ctype != Word || !isVocabWord(cell.word) || cc.Known(cell.word) {
+				continue
+			}
+			if err = cc.AddWord(cell.word, 1); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+	return err
+}
+
+// Calculate sets chances of the chain.
+func (cc *CharChain) Calculate() {
+	cc.c.CalculateCells()
+}
+
+// Known reports whether the word was learned.
+func (cc *CharChain) Known(word string) bool {
+	_, ok := cc.vocab[strings.ToLower(word)]
+	return ok
+}
+
+// GenerateWord invents a word. It returns *AttemptsError if no word
+// satisfies options in given attempts.
+func (cc *CharChain) GenerateWord(rnd *rand.Rand, opts CharOptions) (string, error) {
+	if opts.MinLength < 1 {
+		opts.MinLength = 1
+	}
+	if opts.MaxLength < 1 {
+		opts.MaxLength = 20
+	}
+	if opts.Attempts < 1 {
+		opts.Attempts = 100
+	}
+	for i := 0; i < opts.Attempts; i++ {
+		w, err := cc.walk(rnd, opts.MaxLength)
+		if err != nil {
+			return "", err
+		}
+		n := len([]rune(w))
+		if n == 0 || n < opts.MinLength || (opts.RejectKnown && cc.Known(w)) {
+			continue
+		}
+		return w, nil
+	}
+	return "", &AttemptsError{Attempts: opts.Attempts}
+}
+
+// walk returns characters until the end of the word. Empty result means
+// the word is longer than maxLen.
+func (cc *CharChain) walk(rnd *rand.Rand, maxLen int) (string, error) {
+	runes := make([]rune, 0, maxLen)
+	for len(runes) <= maxLen {
+		cell, err := cc.c.nextCell(cc.state(runes), rnd)
+		if err != nil {
+			return "", err
+		}
+		if cell.ctype == End {
+			return string(runes), nil
+		}
+		runes = append(runes, []rune(cell.word)...)
+	}
+	return "", nil
+}
+
+// state returns core of the last characters. States at the beginning of
+// the word are shorter than order, so they differ from the others.
+func (cc *CharChain) state(runes []rune) string {
+	if len(runes) == 0 {
+		return "*START*"
+	}
+	if len(runes) > cc.order {
+		runes = runes[len(runes)-cc.order:]
+	}
+	return string(runes)
+}
+
+// isVocabWord reports whether the word consists of letters, digits,
+// dashes and underscores and has at least one letter.
+func isVocabWord(w string) bool {
+	letter := false
+	for _, r := range w {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r), r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return letter
+}
@@ -0,0 +1,50 @@
+package markov
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestCharChainAddTextCountsOccurrences(t *testing.T) {
+	cc := NewCharChain(2)
+	if err := cc.AddText("кот кот пёс, 42 кот", 1); err != nil {
+		t.Fatal(err)
+	}
+	cc.Calculate()
+	if cc.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", cc.Len())
+	}
+	counts := make(map[string]uint64)
+	for _, cell := range cc.c.d["*START*"] {
+		counts[cell.word] = cell.count
+	}
+	if counts["к"] != 3 || counts["п"] != 1 {
+		t.Errorf("first letters counted %v, want к 3 times and п once", counts)
+	}
+}
+
+func TestGenerateWord(t *testing.T) {
+	cc := NewCharChain(1)
+	for _, w := range []string{"кот", "код", "тор"} {
+		if err := cc.AddWord(w, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cc.Calculate()
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		w, err := cc.GenerateWord(rnd, CharOptions{MinLength: 2, MaxLength: 6, RejectKnown: true})
+		if err != nil {
+			continue
+		}
+		if n := len([]rune(w)); n < 2 || n > 6 || cc.Known(w) {
+			t.Errorf("GenerateWord() = %q breaks options", w)
+		}
+	}
+	_, err := cc.GenerateWord(rnd, CharOptions{MinLength: 10, MaxLength: 12, Attempts: 5})
+	var aerr *AttemptsError
+	if !errors.As(err, &aerr) || aerr.Attempts != 5 || !errors.Is(err, ErrRejected) {
+		t.Errorf("impossible word error = %v, want AttemptsError of 5 attempts", err)
+	}
+}
@@ -0,0 +1,90 @@
+package markov
+
+import (
+	"fmt"
+	"math"
+)
+
+// Step is a transition of a phrase through the chain.
+type Step struct {
+	// State is the core the word follows.
+	State string `json:"state"`
+	// Word is the chosen word, *END* for the end of the sentence.
+	Word string `json:"word"`
+	// Count is how many times the word followed the state in learned texts.
+	Count uint64 `json:"count"`
+	// Probability of the word in the state, in range (0, 1]. Traces of
+	// generation take temperature and repetition rules into account.
+	Probability float64 `json:"probability"`
+	// Alternatives is amount of other words possible in the state.
+	Alternatives int `json:"alternatives"`
+	// LogProb is natural logarithm of the phrase probability up to the step.
+	LogProb float64 `json:"log_prob"`
+}
+
+// Explain returns transitions producing the words by learned chances. The
+// first word which doesn't start a sentence is treated as GenerateOptions.Start
+// and has no step, each core it may start is tried until the rest of words
+// matches. The end of sentence is the last step if the phrase may end.
+func (c *Chain) Explain(words []string) ([]Step, error) {
+	if len(words) == 0 {
+		return nil, ErrEmptyText
+	}
+	if _, _, ok := c.cellOf("*START*", words[0]); ok {
+		return c.explain("*START*", words)
+	}
+	cores, err := c.startCores(words[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, core := range cores {
+		var steps []Step
+		if steps, err = c.explain(core, words[1:]); err == nil {
+			return steps, nil
+		}
+	}
+	return nil, err
+}
+
+// explain returns transitions producing the words from the state.
+func (c *Chain) explain(state string, words []string) ([]Step, error) {
+	steps := make([]Step, 0, len(words)+1)
+	var logProb float64
+	for _, w := range words {
+		cell, n, ok := c.cellOf(state, w)
+		if !ok {
+			return nil, fmt.Errorf("word %q after %q: %w", w, state, ErrNotFound)
+		}
+		steps = append(steps, c.step(state, cell, n, &logProb))
+		state = c.nextCore(state, w)
+	}
+	if cell, n, ok := c.cellOf(state, "*END*"); ok {
+		steps = append(steps, c.step(state, cell, n, &logProb))
+	}
+	return steps, nil
+}
+
+// cellOf returns cell of the word following the core and amount of cells of the core.
+func (c *Chain) cellOf(core, word string) (Cell, int, bool) {
+	cells := c.d[core]
+	for _, cell := range cells {
+		if cell.word == word {
+			return cell, len(cells), true
+		}
+	}
+	return Cell{}, len(cells), false
+}
+
+// step describes transition by the cell adding its probability to logProb.
+func (c *Chain) step(state string, cell Cell, cells int, logProb *float64) Step {
+	p := cell.chance / 100
+	*logProb += math.Log(p)
+	return Step{
+		State:        state,
+		Word:         cell.word,
+		Count:        cell.count,
+		Probability:  p,
+		Alternatives: cells - 1,
+		LogProb:      *logProb,
+	}
+}
@@ -0,0 +1,50 @@
+package markov
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	c := NewChainOrder(2)
+	for _, text := range []string{"a b c.", "x b d.", "x b d."} {
+		if err := c.ParseText(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.CalculateCells()
+
+	tests := []struct {
+		words  []string
+		states []string
+	}{
+		{[]string{"a", "b", "c"}, []string{"*START*", "*START* a", "a b", "b c"}},
+		// b starts cores "a b" and "x b", only the second one is followed by d
+		{[]string{"b", "d"}, []string{"x b", "b d"}},
+		{[]string{"b", "c"}, []string{"a b", "b c"}},
+	}
+	for _, tt := range tests {
+		steps, err := c.Explain(tt.words)
+		if err != nil {
+			t.Errorf("Explain(%v) failed: %v", tt.words, err)
+			continue
+		}
+		states := make([]string, 0, len(steps))
+		for _, s := range steps {
+			states = append(states, s.State)
+		}
+		if !reflect.DeepEqual(states, tt.states) || steps[len(steps)-1].Word != "*END*" {
+			t.Errorf("Explain(%v) = %v, want states %v ending with *END*", tt.words, steps, tt.states)
+		}
+	}
+
+	for _, words := range [][]string{{"b", "e"}, {"e"}, {"a", "c"}} {
+		if _, err := c.Explain(words); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Explain(%v) error = %v, want ErrNotFound", words, err)
+		}
+	}
+	if _, err := c.Explain(nil); !errors.Is(err, ErrEmptyText) {
+		t.Errorf("Explain(nil) error = %v, want ErrEmptyText", err)
+	}
+}
@@ -0,0 +1,208 @@
+package markov
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportOptions limits the part of the chain to export.
+type ExportOptions struct {
+	// Root sets the word to export neighborhood of. Empty means whole chain.
+	Root string
+	// Depth of the neighborhood around Root in both directions.
+	Depth int
+	// MinCount skips transitions seen fewer times.
+	MinCount uint64
+}
+
+// edge is a single exported transition.
+type edge struct {
+	from   string
+	to     string
+	count  uint64
+	chance float64
+}
+
+// WriteDOT writes the chain in Graphviz DOT format.
+func (c *Chain) WriteDOT(w io.Writer, opts ExportOptions) error {
+	edges, err := c.exportEdges(opts)
+	if err != nil {
+		return err
+	}
+	b := &strings.Builder{}
+	b.WriteString("digraph chain {\n")
+	for _, e := range edges {
+		fmt.Fprintf(b, "\t%s -> %s [label=\"%d (%.2f%%)\", weight=%d];\n",
+			dotQuote(e.from), dotQuote(e.to), e.count, e.chance, e.count)
+	}
+	b.WriteString("}\n")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuoteReplacer escapes characters special inside DOT quoted strings.
+var dotQuoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// dotQuote quotes DOT identifier escaping only quotes and backslashes,
+// Graphviz reads the rest including UTF-8 as is.
+func dotQuote(s string) string {
+	return `"` + dotQuoteReplacer.Replace(s) + `"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID string `xml:"id,attr"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the chain in GraphML format.
+func (c *Chain) WriteGraphML(w io.Writer, opts ExportOptions) error {
+	edges, err := c.exportEdges(opts)
+	if err != nil {
+		return err
+	}
+	g := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "count", For: "edge", Name: "count", Type: "long"},
+			{ID: "chance", For: "edge", Name: "chance", Type: "double"},
+		},
+		Graph: graphMLGraph{ID: "chain", EdgeDefault: "directed"},
+	}
+	nodes := make(map[string]struct{})
+	for _, e := range edges {
+		for _, n := range []string{e.from, e.to} {
+			if _, ok := nodes[n]; !ok {
+				nodes[n] = struct{}{}
+				g.Graph.Nodes = append(g.Graph.Nodes, graphMLNode{n})
+			}
+		}
+		g.Graph.Edges = append(g.Graph.Edges, graphMLEdge{
+			Source: e.from,
+			Target: e.to,
+			Data: []graphMLData{
+				{"count", strconv.FormatUint(e.count, 10)},
+				{"chance", strconv.FormatFloat(e.chance, 'f', 4, 64)},
+			},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(g); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// exportEdges returns sorted transitions matching the options.
+// Edges lead from core to the core it's followed by.
+func (c *Chain) exportEdges(opts ExportOptions) ([]edge, error) {
+	edges := make([]edge, 0)
+	for core, cells := range c.d {
+		for _, cell := range cells {
+			if cell.count < opts.MinCount {
+				continue
+			}
+			to := cell.word
+			if cell.ctype != End {
+				to = c.nextCore(core, cell.word)
+			}
+			edges = append(edges, edge{core, to, cell.count, cell.chance})
+		}
+	}
+
+	if opts.Root != "" {
+		keep := neighborhood(edges, c.normalize(opts.Root), opts.Depth)
+		if len(keep) == 0 {
+			return nil, fmt.Errorf("root %q: %w", opts.Root, ErrNotFound)
+		}
+		kept := edges[:0]
+		for _, e := range edges {
+			_, okFrom := keep[e.from]
+			_, okTo := keep[e.to]
+			if okFrom && okTo {
+				kept = append(kept, e)
+			}
+		}
+		edges = kept
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].from != edges[j].from {
+			return edges[i].from < edges[j].from
+		}
+		return edges[i].to < edges[j].to
+	})
+	return edges, nil
+}
+
+// neighborhood returns cores reachable in depth steps in both directions
+// from cores ending with root word.
+func neighborhood(edges []edge, root string, depth int) map[string]struct{} {
+	adj := make(map[string][]string)
+	seen := make(map[string]struct{})
+	front := make([]string, 0)
+	for _, e := range edges {
+		adj[e.from] = append(adj[e.from], e.to)
+		adj[e.to] = append(adj[e.to], e.from)
+		for _, n := range []string{e.from, e.to} {
+			if _, ok := seen[n]; !ok && (n == root || strings.HasSuffix(n, " "+root)) {
+				seen[n] = struct{}{}
+				front = append(front, n)
+			}
+		}
+	}
+
+	for i := 0; i < depth && len(front) > 0; i++ {
+		next := make([]string, 0)
+		for _, n := range front {
+			for _, m := range adj[n] {
+				if _, ok := seen[m]; !ok {
+					seen[m] = struct{}{}
+					next = append(next, m)
+				}
+			}
+		}
+		front = next
+	}
+	return seen
+}
@@ -0,0 +1,37 @@
+package markov
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDotQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"кот", `"кот"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`a\b`, `"a\\b"`},
+		{"*START*", `"*START*"`},
+	}
+	for _, tt := range tests {
+		if got := dotQuote(tt.in); got != tt.want {
+			t.Errorf("dotQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteDOTKeepsUTF8(t *testing.T) {
+	c := NewChain()
+	if err := c.ParseText("кот спит."); err != nil {
+		t.Fatal(err)
+	}
+	c.CalculateCells()
+	b := &strings.Builder{}
+	if err := c.WriteDOT(b, ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"кот" -> "спит"`) {
+		t.Errorf("DOT must keep UTF-8 words as is, got:\n%s", b)
+	}
+}
@@ -0,0 +1,331 @@
+package markov
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxWords limits the length of a single random walk.
+const maxWords = 30
+
+// Candidate is a generated phrase with the score given by a Ranker.
+type Candidate struct {
+	Words []string
+	Score float64
+	// Trace holds steps of the walk if GenerateOptions.Trace is set.
+	Trace []Step
+}
+
+// String returns the candidate as a sentence.
+func (c Candidate) String() string {
+	return strings.Join(c.Words, " ") + "."
+}
+
+// GenerateOptions tunes generation. Zero value makes a single
+// walk of at most 30 words without repetition control.
+type GenerateOptions struct {
+	// Candidates is amount of walks to choose the best one from.
+	Candidates int
+	// Ranker scores candidates. Nil means the first candidate wins.
+	Ranker Ranker
+
+	// MaxWords limits amount of words in phrase.
+	MaxWords int
+	// MaxRepeat limits how many times the same word may appear in phrase.
+	MaxRepeat int
+	// NoRepeatNgram bans repeating any n-gram of this size.
+	NoRepeatNgram int
+	// RepeatPenalty in range (0, 1) multiplies chance of the word for each
+	// time it was already used.
+	RepeatPenalty float64
+	// StopOnCycle ends the phrase once its tail repeats itself,
+	// dropping the repeated part.
+	StopOnCycle bool
+	// Temperature reshapes chances of words: below 1 favours likely words,
+	// above 1 flattens the distribution. Zero keeps learned chances.
+	Temperature float64
+
+	// Start is the first word of the phrase. Empty means the phrase
+	// starts as a sentence.
+	Start string
+
+	// Accept rejects candidates it returns false for. Nil accepts all.
+	Accept func(words []string) bool
+
+	// Seed of random walks, current time if zero. Candidates use Seed,
+	// Seed+1 and so on, so the same seed gives the same phrase.
+	Seed int64
+	// Trace records steps of the walk in Candidate.Trace.
+	Trace bool
+}
+
+// Generate makes a single random walk through the chain.
+func (c *Chain) Generate() (Candidate, error) {
+	return c.GenerateWith(GenerateOptions{})
+}
+
+// GenerateBest produces n candidates in parallel and returns the one
+// with the highest score given by r. If r is nil the first generated
+// candidate is returned.
+func (c *Chain) GenerateBest(n int, r Ranker) (Candidate, error) {
+	return c.GenerateWith(GenerateOptions{Candidates: n, Ranker: r})
+}
+
+// GenerateWith produces opts.Candidates candidates in parallel and
+// returns the best one.
+func (c *Chain) GenerateWith(opts GenerateOptions) (Candidate, error) {
+	n := opts.Candidates
+	if n < 1 {
+		n = 1
+	}
+	type result struct {
+		cand Candidate
+		err  error
+	}
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	// results are indexed by candidate, so ties are resolved
+	// the same way for the same seed
+	results := make([]result, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int, rnd *rand.Rand) {
+			defer wg.Done()
+			words, trace, err := c.walk(rnd, opts)
+			if err == nil && opts.Accept != nil && !opts.Accept(words) {
+				err = ErrRejected
+			}
+			cand := Candidate{Words: words, Trace: trace}
+			if err == nil && opts.Ranker != nil {
+				cand.Score = opts.Ranker.Rank(c, words)
+			}
+			results[i] = result{cand, err}
+		}(i, rand.New(rand.NewSource(seed+int64(i))))
+	}
+	wg.Wait()
+
+	var best Candidate
+	var lastErr error
+	found := false
+	for _, res := range results {
+		if res.err != nil {
+			lastErr = res.err
+			continue
+		}
+		if !found || res.cand.Score > best.Score {
+			best = res.cand
+			found = true
+		}
+	}
+	if !found {
+		return Candidate{}, lastErr
+	}
+	return best, nil
+}
+
+// walk goes from *START* until the end of sentence or the words limit.
+// It returns an error only if no words could be generated. Steps are
+// recorded if opts.Trace is set, the start word has no step.
+func (c *Chain) walk(rnd *rand.Rand, opts GenerateOptions) ([]string, []Step, error) {
+	limit := opts.MaxWords
+	if limit < 1 {
+		limit = maxWords
+	}
+	words := make([]string, 0)
+	var trace []Step
+	var logProb float64
+	used := make(map[string]int)
+	prev := "*START*"
+	if opts.Start != "" {
+		start := c.normalize(strings.TrimSpace(opts.Start))
+		core, err := c.startCore(start, rnd)
+		if err != nil {
+			return nil, nil, err
+		}
+		words = append(words, start)
+		used[start]++
+		prev = core
+	}
+	for len(words) < limit {
+		cell, p, alt, err := c.nextAllowedCell(prev, words, used, rnd, opts)
+		if err != nil {
+			if len(words) == 0 {
+				return nil, nil, err
+			}
+			break
+		}
+		if opts.Trace {
+			logProb += math.Log(p)
+			trace = append(trace, Step{
+				State:        prev,
+				Word:         cell.word,
+				Count:        cell.count,
+				Probability:  p,
+				Alternatives: alt,
+				LogProb:      logProb,
+			})
+		}
+		if cell.ctype == End {
+			break
+		}
+		words = append(words, cell.word)
+		used[cell.word]++
+		prev = c.nextCore(prev, cell.word)
+		if opts.StopOnCycle {
+			if p := cyclePeriod(words); p > 0 {
+				words = words[:len(words)-p]
+				if opts.Trace {
+					trace = trace[:len(trace)-p]
+				}
+				break
+			}
+		}
+	}
+	return words, trace, nil
+}
+
+// startCore returns core following the start word. The word starts
+// a sentence if possible, otherwise random core ending with it is used.
+func (c *Chain) startCore(start string, rnd *rand.Rand) (string, error) {
+	cores, err := c.startCores(start)
+	if err != nil {
+		return "", err
+	}
+	return cores[rnd.Intn(len(cores))], nil
+}
+
+// startCores returns sorted cores which may follow the start word.
+func (c *Chain) startCores(start string) ([]string, error) {
+	core := c.nextCore("*START*", start)
+	if _, ok := c.d[core]; ok {
+		return []string{core}, nil
+	}
+	cores := make([]string, 0)
+	for k := range c.d {
+		if k == start || strings.HasSuffix(k, " "+start) {
+			cores = append(cores, k)
+		}
+	}
+	if len(cores) == 0 {
+		return nil, fmt.Errorf("start %q: %w", start, ErrNotFound)
+	}
+	sort.Strings(cores)
+	return cores, nil
+}
+
+// reshapes reports whether temperature changes chances.
+func reshapes(temperature float64) bool {
+	return temperature > 0 && temperature != 1
+}
+
+// nextAllowedCell picks next cell of core taking repetition rules into
+// account. It also returns probability of the cell being picked and amount
+// of other cells which could be picked.
+func (c *Chain) nextAllowedCell(core string, words []string, used map[string]int, rnd *rand.Rand, opts GenerateOptions) (Cell, float64, int, error) {
+	if opts.MaxRepeat < 1 && opts.NoRepeatNgram < 1 && opts.RepeatPenalty <= 0 && !reshapes(opts.Temperature) {
+		cell, err := c.nextCell(core, rnd)
+		if err != nil {
+			return cell, 0, 0, err
+		}
+		return cell, cell.chance / 100, len(c.d[core]) - 1, nil
+	}
+	if len(c.d) == 0 {
+		return Cell{}, 0, 0, ErrEmptyChain
+	}
+	cells, err := c.GetCells(core)
+	if err != nil {
+		return Cell{}, 0, 0, fmt.Errorf("%w: %w", ErrDeadEnd, err)
+	}
+
+	weights := make([]float64, len(cells))
+	var total float64
+	allowed := 0
+	for i, cell := range cells {
+		w := cell.chance
+		if reshapes(opts.Temperature) {
+			w = math.Pow(w, 1/opts.Temperature)
+		}
+		if cell.ctype == Word {
+			n := used[cell.word]
+			switch {
+			case opts.MaxRepeat > 0 && n >= opts.MaxRepeat:
+				w = 0
+			case opts.NoRepeatNgram > 0 && repeatsNgram(words, cell.word, opts.NoRepeatNgram):
+				w = 0
+			case opts.RepeatPenalty > 0 && n > 0:
+				w *= math.Pow(opts.RepeatPenalty, float64(n))
+			}
+		}
+		weights[i] = w
+		total += w
+		if w > 0 {
+			allowed++
+		}
+	}
+	if total <= 0 {
+		return Cell{}, 0, 0, fmt.Errorf("%w: every continuation of %q is banned", ErrDeadEnd, core)
+	}
+
+	pick := rnd.Float64() * total
+	last := -1
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		last = i
+		if pick < w {
+			return cells[i], w / total, allowed - 1, nil
+		}
+		pick -= w
+	}
+	return cells[last], weights[last] / total, allowed - 1, nil
+}
+
+// repeatsNgram reports whether appending word to words produces
+// an n-gram which already occurs in words.
+func repeatsNgram(words []string, word string, n int) bool {
+	if len(words) < n || n < 1 {
+		return false
+	}
+	tail := append(append(make([]string, 0, n), words[len(words)-n+1:]...), word)
+	for i := 0; i+n <= len(words); i++ {
+		match := true
+		for j := 0; j < n; j++ {
+			if words[i+j] != tail[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// cyclePeriod returns length of the repeated tail of words
+// (e.g. 2 for "я не я не"), or 0 if the tail doesn't repeat.
+func cyclePeriod(words []string) int {
+	for p := 1; p*2 <= len(words); p++ {
+		cycle := true
+		for i := 0; i < p; i++ {
+			if words[len(words)-1-i] != words[len(words)-1-p-i] {
+				cycle = false
+				break
+			}
+		}
+		if cycle {
+			return p
+		}
+	}
+	return 0
+}
@@ -0,0 +1,178 @@
+package markov
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRepeatsNgram(t *testing.T) {
+	tests := []struct {
+		words string
+		word  string
+		n     int
+		want  bool
+	}{
+		{"", "a", 1, false},
+		{"a b", "a", 1, true},
+		{"a b", "c", 1, false},
+		{"a b c", "b", 2, false},
+		{"a b c a", "b", 2, true},
+		{"a b a", "b", 2, true},
+		{"a b c a b", "c", 3, true},
+		{"a b c a b", "d", 3, false},
+		{"a b", "a", 3, false},
+		{"a b", "a", 0, false},
+	}
+	for _, tt := range tests {
+		if got := repeatsNgram(strings.Fields(tt.words), tt.word, tt.n); got != tt.want {
+			t.Errorf("repeatsNgram(%q, %q, %d) = %v, want %v", tt.words, tt.word, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCyclePeriod(t *testing.T) {
+	tests := []struct {
+		words string
+		want  int
+	}{
+		{"", 0},
+		{"я", 0},
+		{"я я", 1},
+		{"я не я не", 2},
+		{"и я не я не", 2},
+		{"a b c a b c", 3},
+		{"a b c a b", 0},
+		{"a b a", 0},
+	}
+	for _, tt := range tests {
+		if got := cyclePeriod(strings.Fields(tt.words)); got != tt.want {
+			t.Errorf("cyclePeriod(%q) = %d, want %d", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestTraceLogProb(t *testing.T) {
+	c := NewChain()
+	for _, text := range []string{"кот спит на диване.", "кот ест рыбу.", "пёс спит во дворе.", "пёс ест кость, кот ест рыбу."} {
+		if err := c.ParseText(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.CalculateCells()
+
+	tests := []GenerateOptions{
+		{},
+		{Start: "ест"},
+		{Temperature: 1.5, RepeatPenalty: 0.5, MaxRepeat: 1},
+		{Temperature: 0.5, NoRepeatNgram: 2, StopOnCycle: true},
+	}
+	for _, opts := range tests {
+		for seed := int64(1); seed <= 10; seed++ {
+			opts.Seed, opts.Trace = seed, true
+			cand, err := c.GenerateWith(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sum float64
+			for i, s := range cand.Trace {
+				if s.Probability <= 0 || s.Probability > 1 {
+					t.Fatalf("%+v: step %d probability %g out of (0, 1]", opts, i, s.Probability)
+				}
+				sum += math.Log(s.Probability)
+				if math.Abs(s.LogProb-sum) > 1e-9 {
+					t.Fatalf("%+v: step %d log-probability %g, want sum %g", opts, i, s.LogProb, sum)
+				}
+			}
+			if opts.Temperature != 0 || len(cand.Trace) == 0 {
+				continue
+			}
+			// without rules the trace matches learned chances
+			steps, err := c.Explain(cand.Words)
+			if err != nil {
+				t.Fatal(err)
+			}
+			last, want := cand.Trace[len(cand.Trace)-1], steps[len(steps)-1]
+			if len(steps) != len(cand.Trace) || math.Abs(last.LogProb-want.LogProb) > 1e-9 {
+				t.Errorf("%+v: trace %v, explained %v", opts, cand.Trace, steps)
+			}
+		}
+	}
+}
+
+func TestGenerateWithRepetitionRules(t *testing.T) {
+	c := NewChain()
+	for _, text := range []string{"и и и и.", "и да и нет и да и нет."} {
+		if err := c.ParseText(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.CalculateCells()
+
+	counts := func(words []string) map[string]int {
+		m := make(map[string]int)
+		for _, w := range words {
+			m[w]++
+		}
+		return m
+	}
+	tests := []struct {
+		name  string
+		opts  GenerateOptions
+		check func(words []string) bool
+	}{
+		{"max words", GenerateOptions{MaxWords: 7}, func(words []string) bool {
+			return len(words) <= 7
+		}},
+		{"max repeat", GenerateOptions{MaxRepeat: 2}, func(words []string) bool {
+			for _, n := range counts(words) {
+				if n > 2 {
+					return false
+				}
+			}
+			return true
+		}},
+		{"no repeat ngram", GenerateOptions{NoRepeatNgram: 2}, func(words []string) bool {
+			seen := make(map[string]bool)
+			for i := 0; i+2 <= len(words); i++ {
+				bigram := strings.Join(words[i:i+2], " ")
+				if seen[bigram] {
+					return false
+				}
+				seen[bigram] = true
+			}
+			return true
+		}},
+		{"stop on cycle", GenerateOptions{StopOnCycle: true}, func(words []string) bool {
+			return cyclePeriod(words) == 0
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the chain loops, so phrases without the rule break it
+			broken := false
+			for seed := int64(1); seed <= 50; seed++ {
+				cand, err := c.GenerateWith(GenerateOptions{Seed: seed})
+				if err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				broken = broken || !tt.check(cand.Words)
+			}
+			if !broken {
+				t.Fatal("no phrase breaks the rule without it")
+			}
+			for seed := int64(1); seed <= 50; seed++ {
+				opts := tt.opts
+				opts.Seed = seed
+				cand, err := c.GenerateWith(opts)
+				if err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				if len(cand.Words) == 0 || !tt.check(cand.Words) {
+					t.Fatalf("seed %d: %q breaks the rule", seed, cand.Words)
+				}
+			}
+		})
+	}
+
+}
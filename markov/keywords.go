@@ -0,0 +1,70 @@
+package markov
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Keyword is a word of a text scored by TF-IDF against the learned texts.
+type Keyword struct {
+	Word  string
+	Score float64
+}
+
+// countDocument counts the text of distinct lowercased words for TF-IDF.
+func (c *Chain) countDocument(words map[string]struct{}) {
+	if len(words) == 0 {
+		return
+	}
+	c.docs++
+	for w := range words {
+		if !isPlaceholder(w) {
+			c.df[w]++
+		}
+	}
+}
+
+// Documents returns amount of parsed texts.
+func (c *Chain) Documents() uint64 {
+	return c.docs
+}
+
+// DocumentFrequency returns amount of parsed texts containing the word.
+func (c *Chain) DocumentFrequency(word string) uint64 {
+	return c.df[strings.ToLower(word)]
+}
+
+// Keywords returns up to n words of the text ordered by descending TF-IDF.
+// Words absent in the learned texts are skipped since the chain can't
+// produce them. Chains saved without document frequencies return no keywords.
+func (c *Chain) Keywords(text string, n int) []Keyword {
+	if c.docs == 0 || n < 1 {
+		return nil
+	}
+	words := tokenize(text)
+	tf := make(map[string]int, len(words))
+	for _, w := range words {
+		if c.df[w] > 0 {
+			tf[w]++
+		}
+	}
+	keywords := make([]Keyword, 0, len(tf))
+	for w, f := range tf {
+		idf := math.Log(float64(c.docs+1) / float64(c.df[w]))
+		keywords = append(keywords, Keyword{
+			Word:  w,
+			Score: float64(f) / float64(len(words)) * idf,
+		})
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].Score != keywords[j].Score {
+			return keywords[i].Score > keywords[j].Score
+		}
+		return keywords[i].Word < keywords[j].Word
+	})
+	if len(keywords) > n {
+		keywords = keywords[:n]
+	}
+	return keywords
+}
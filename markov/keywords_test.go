@@ -0,0 +1,45 @@
+package markov
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKeywords(t *testing.T) {
+	c := NewChain()
+	if len(c.Keywords("кот", 3)) != 0 {
+		t.Error("chain without documents must return no keywords")
+	}
+	for _, text := range []string{"кот спит.", "кот ест рыбу.", "пёс спит.", "<NICK> ест."} {
+		if err := c.ParseText(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Documents() != 4 || c.DocumentFrequency("КОТ") != 2 || c.DocumentFrequency("<NICK>") != 0 {
+		t.Errorf("documents %d, df of кот %d, of <NICK> %d, want 4, 2, 0",
+			c.Documents(), c.DocumentFrequency("КОТ"), c.DocumentFrequency("<NICK>"))
+	}
+
+	// 5 words with unknown дом, рыбу twice in 1 of 4 documents, кот and ест
+	// once in 2 of 4
+	got := c.Keywords("Кот ест рыбу, рыбу! Дом", 5)
+	want := []Keyword{
+		{"рыбу", 2.0 / 5 * math.Log(5.0/1)},
+		{"ест", 1.0 / 5 * math.Log(5.0/2)},
+		{"кот", 1.0 / 5 * math.Log(5.0/2)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Keywords() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].Word != want[i].Word || math.Abs(got[i].Score-want[i].Score) > 1e-9 {
+			t.Errorf("keyword %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if got := c.Keywords("кот ест рыбу", 1); len(got) != 1 || got[0].Word != "рыбу" {
+		t.Errorf("Keywords(n=1) = %v, want рыбу only", got)
+	}
+	if got := c.Keywords("дом", 3); len(got) != 0 {
+		t.Errorf("Keywords() of unknown words = %v, want none", got)
+	}
+}
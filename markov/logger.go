@@ -0,0 +1,16 @@
+package markov
+
+// Logger is used by the package to report what's going on.
+// Both *logrus.Logger and *logrus.Entry satisfy it.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+}
+
+// nopLogger discards all messages.
+type nopLogger struct{}
+
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+func (nopLogger) Infof(format string, args ...interface{})  {}
+func (nopLogger) Warnf(format string, args ...interface{})  {}
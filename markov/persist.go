@@ -0,0 +1,134 @@
+package markov
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// formatVersion is a version of saved chain format.
+const formatVersion = 1
+
+// savedChain is a serialized chain.
+type savedChain struct {
+	Version  int                    `json:"version"`
+	Order    int                    `json:"order"`
+	KeepCase bool                   `json:"keep_case"`
+	Total    uint64                 `json:"total"`
+	Cells    map[string][]savedCell `json:"cells"`
+	// Docs and DocFreq are absent in chains saved before keywords support.
+	Docs    uint64            `json:"docs,omitempty"`
+	DocFreq map[string]uint64 `json:"df,omitempty"`
+}
+
+// savedCell is a serialized cell. Chance is recalculated on load.
+type savedCell struct {
+	Word   string   `json:"w"`
+	Count  uint64   `json:"c"`
+	Type   CellType `json:"t"`
+	Weight float64  `json:"wt"`
+}
+
+// savedChars is a serialized character chain.
+type savedChars struct {
+	Version int        `json:"version"`
+	Order   int        `json:"order"`
+	Vocab   []string   `json:"vocab"`
+	Chain   savedChain `json:"chain"`
+}
+
+// Save writes the chain as JSON.
+func (c *Chain) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c.saved())
+}
+
+// Load reads chain written by Save and calculates its chances.
+func Load(r io.Reader) (*Chain, error) {
+	var s savedChain
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("decode chain: %w", err)
+	}
+	return chainFromSaved(s)
+}
+
+// Save writes the character chain as JSON.
+func (cc *CharChain) Save(w io.Writer) error {
+	s := savedChars{
+		Version: formatVersion,
+		Order:   cc.order,
+		Vocab:   make([]string, 0, len(cc.vocab)),
+		Chain:   cc.c.saved(),
+	}
+	for word := range cc.vocab {
+		s.Vocab = append(s.Vocab, word)
+	}
+	sort.Strings(s.Vocab)
+	return json.NewEncoder(w).Encode(s)
+}
+
+// LoadCharChain reads character chain written by CharChain.Save and
+// calculates its chances.
+func LoadCharChain(r io.Reader) (*CharChain, error) {
+	var s savedChars
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("decode char chain: %w", err)
+	}
+	if s.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported char chain format version %d", s.Version)
+	}
+	c, err := chainFromSaved(s.Chain)
+	if err != nil {
+		return nil, err
+	}
+	cc := NewCharChain(s.Order)
+	cc.c = c
+	for _, word := range s.Vocab {
+		cc.vocab[word] = struct{}{}
+	}
+	return cc, nil
+}
+
+func (c *Chain) saved() savedChain {
+	s := savedChain{
+		Version:  formatVersion,
+		Order:    c.order,
+		KeepCase: c.keepCase,
+		Total:    c.totalRecords,
+		Cells:    make(map[string][]savedCell, len(c.d)),
+		Docs:     c.docs,
+		DocFreq:  c.df,
+	}
+	for core, cells := range c.d {
+		sc := make([]savedCell, len(cells))
+		for i, cell := range cells {
+			sc[i] = savedCell{cell.word, cell.count, cell.ctype, cell.weight}
+		}
+		s.Cells[core] = sc
+	}
+	return s
+}
+
+func chainFromSaved(s savedChain) (*Chain, error) {
+	if s.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported chain format version %d", s.Version)
+	}
+	c := NewChainOrder(s.Order)
+	c.keepCase = s.KeepCase
+	c.totalRecords = s.Total
+	c.docs = s.Docs
+	for w, n := range s.DocFreq {
+		c.df[w] = n
+	}
+	for core, cells := range s.Cells {
+		for _, sc := range cells {
+			cell := Cell{word: sc.Word, count: sc.Count, ctype: sc.Type, weight: sc.Weight}
+			if !cell.Valid() {
+				return nil, fmt.Errorf("core %q, word %q: %w", core, sc.Word, ErrInvalidCell)
+			}
+			c.d[core] = append(c.d[core], cell)
+		}
+	}
+	c.CalculateCells()
+	return c, nil
+}
@@ -0,0 +1,95 @@
+package markov
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	c := NewChainOrder(2)
+	c.SetKeepCase(true)
+	for _, s := range []string{"The cat sleeps.", "The cat eats fish.", "<NICK>: the dog barks."} {
+		if err := c.ParseTextWeighted(s, 1.5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.CalculateCells()
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"version":1`) {
+		t.Errorf("saved chain has no format version: %s", buf.String())
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Order() != 2 || !loaded.KeepCase() || loaded.GetTotalRecords() != c.GetTotalRecords() {
+		t.Errorf("loaded order %d, keep case %t, records %d", loaded.Order(), loaded.KeepCase(), loaded.GetTotalRecords())
+	}
+	if loaded.Documents() != 3 || loaded.DocumentFrequency("the") != 3 || loaded.DocumentFrequency("cat") != 2 {
+		t.Errorf("loaded %d documents, df of the %d, of cat %d",
+			loaded.Documents(), loaded.DocumentFrequency("the"), loaded.DocumentFrequency("cat"))
+	}
+	if !reflect.DeepEqual(loaded.d, c.d) {
+		t.Errorf("loaded cells %v, want %v", loaded.d, c.d)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, data string
+	}{
+		{"not json", "chain"},
+		{"no version", `{"order": 1, "cells": {}}`},
+		{"future version", `{"version": 2, "order": 1, "cells": {}}`},
+		{"invalid cell", `{"version": 1, "order": 1, "cells": {"*START*": [{"w": "", "c": 1, "t": 1, "wt": 1}]}}`},
+	}
+	for _, tt := range tests {
+		if _, err := Load(strings.NewReader(tt.data)); err == nil {
+			t.Errorf("%s: Load must fail", tt.name)
+		}
+	}
+}
+
+func TestLoadWithoutDocuments(t *testing.T) {
+	// chains saved before keywords support have no document counters
+	data := `{"version": 1, "order": 1, "total": 2, "cells": {
+		"*START*": [{"w": "cat", "c": 1, "t": 1, "wt": 1}],
+		"cat": [{"w": "*END*", "c": 1, "t": 2, "wt": 1}]}}`
+	c, err := Load(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Documents() != 0 || len(c.Keywords("cat", 3)) != 0 {
+		t.Errorf("chain without documents has %d documents", c.Documents())
+	}
+	if cand, err := c.Generate(); err != nil || cand.String() != "cat." {
+		t.Errorf("Generate() = %q, %v", cand.String(), err)
+	}
+}
+
+func TestCharChainSaveLoad(t *testing.T) {
+	cc := NewCharChain(2)
+	if err := cc.AddText("кот котик котенок", 1); err != nil {
+		t.Fatal(err)
+	}
+	cc.Calculate()
+	var buf bytes.Buffer
+	if err := cc.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCharChain(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Order() != 2 || loaded.Len() != cc.Len() || !loaded.Known("котик") {
+		t.Errorf("loaded order %d, %d words", loaded.Order(), loaded.Len())
+	}
+	if !reflect.DeepEqual(loaded.c.d, cc.c.d) {
+		t.Error("loaded char chain differs")
+	}
+}
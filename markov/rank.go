@@ -0,0 +1,149 @@
+package markov
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Ranker scores generated candidates. Greater score is better.
+type Ranker interface {
+	Rank(c *Chain, words []string) float64
+}
+
+// RankerFunc allows to use ordinary functions as Ranker.
+type RankerFunc func(c *Chain, words []string) float64
+
+// Rank calls f(c, words).
+func (f RankerFunc) Rank(c *Chain, words []string) float64 {
+	return f(c, words)
+}
+
+// LengthRanker prefers candidates with length close to Target words.
+type LengthRanker struct {
+	Target int
+}
+
+// Rank returns negative distance between candidate length and target.
+func (r LengthRanker) Rank(c *Chain, words []string) float64 {
+	return -math.Abs(float64(len(words) - r.Target))
+}
+
+// LikelihoodRanker prefers candidates the chain is most likely to produce.
+// If Normalize is set, log-likelihood is divided by amount of transitions,
+// so long candidates aren't penalized.
+type LikelihoodRanker struct {
+	Normalize bool
+}
+
+// Rank returns log-likelihood of the candidate explained by the chain.
+// Candidate starting with a word which doesn't start a sentence is scored
+// from the core of that word, candidate which can't end has no end
+// transition. Candidate the chain can't produce scores -Inf.
+func (r LikelihoodRanker) Rank(c *Chain, words []string) float64 {
+	steps, err := c.Explain(words)
+	if err != nil || len(steps) == 0 {
+		return math.Inf(-1)
+	}
+	sum := steps[len(steps)-1].LogProb
+	if r.Normalize {
+		sum /= float64(len(steps))
+	}
+	return sum
+}
+
+// NoveltyRanker prefers candidates which don't repeat the corpus.
+// The score is a share of candidate's n-grams never seen in the corpus.
+type NoveltyRanker struct {
+	n    int
+	mu   sync.RWMutex
+	seen map[uint64]struct{}
+}
+
+// NewNoveltyRanker creates ranker comparing n-grams of size n.
+func NewNoveltyRanker(n int) *NoveltyRanker {
+	if n < 1 {
+		n = 1
+	}
+	return &NoveltyRanker{n: n, seen: make(map[uint64]struct{})}
+}
+
+// Add remembers n-grams of corpus text.
+func (r *NoveltyRanker) Add(text string) {
+	words := tokenize(text)
+	r.mu.Lock()
+	for i := 0; i+r.n <= len(words); i++ {
+		r.seen[hashWords(words[i:i+r.n])] = struct{}{}
+	}
+	r.mu.Unlock()
+}
+
+// Rank returns share of unseen n-grams in range [0, 1].
+func (r *NoveltyRanker) Rank(c *Chain, words []string) float64 {
+	if len(words) < r.n {
+		return 0
+	}
+	var total, novel int
+	r.mu.RLock()
+	for i := 0; i+r.n <= len(words); i++ {
+		total++
+		if _, ok := r.seen[hashWords(words[i:i+r.n])]; !ok {
+			novel++
+		}
+	}
+	r.mu.RUnlock()
+	return float64(novel) / float64(total)
+}
+
+// KeywordRanker prefers candidates which contain more of Keywords.
+type KeywordRanker struct {
+	Keywords []string
+}
+
+// Rank returns share of keywords found in candidate in range [0, 1].
+// Blank keywords are ignored.
+func (r KeywordRanker) Rank(c *Chain, words []string) float64 {
+	set := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		set[w] = struct{}{}
+	}
+	var found, total int
+	for _, k := range r.Keywords {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" {
+			continue
+		}
+		total++
+		if _, ok := set[k]; ok {
+			found++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(found) / float64(total)
+}
+
+// tokenize splits text into words the same way ParseText does.
+func tokenize(s string) []string {
+	s = addSpace(strings.ToLower(s))
+	words := make([]string, 0)
+	for _, w := range strings.Fields(s) {
+		w = strings.TrimRight(w, ".")
+		if len(w) == 0 || (w[len(w)-1] > 32 && w[len(w)-1] < 65) {
+			continue
+		}
+		words = append(words, w)
+	}
+	return words
+}
+
+func hashWords(words []string) uint64 {
+	h := fnv.New64a()
+	for _, w := range words {
+		_, _ = h.Write([]byte(w))
+		_, _ = h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
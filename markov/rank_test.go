@@ -0,0 +1,134 @@
+package markov
+
+import (
+	"math"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// rankChain returns order 1 chain of a tiny fixed corpus.
+func rankChain(t *testing.T) *Chain {
+	c := NewChain()
+	for _, text := range []string{"кот спит.", "кот ест рыбу.", "пёс спит."} {
+		if err := c.ParseText(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.CalculateCells()
+	return c
+}
+
+func TestLengthRanker(t *testing.T) {
+	r := LengthRanker{Target: 3}
+	for n, want := range []float64{-3, -2, -1, 0, -1} {
+		if got := r.Rank(nil, make([]string, n)); got != want {
+			t.Errorf("Rank() of %d words = %g, want %g", n, got, want)
+		}
+	}
+}
+
+func TestLikelihoodRanker(t *testing.T) {
+	c := rankChain(t)
+	ln := math.Log
+	inf := math.Inf(-1)
+	tests := []struct {
+		words []string
+		want  float64
+		steps int
+	}{
+		{[]string{"кот", "спит"}, ln(2.0/3) + ln(1.0/2) + ln(1), 3},
+		{[]string{"пёс", "спит"}, ln(1.0/3) + ln(1) + ln(1), 3},
+		// starts mid-sentence, scored from the core of the word
+		{[]string{"ест", "рыбу"}, ln(1) + ln(1), 2},
+		// truncated walk can't end, no end transition
+		{[]string{"кот", "ест"}, ln(2.0/3) + ln(1.0/2), 2},
+		{[]string{"кот", "рыбу"}, inf, 1},
+		{[]string{"кит"}, inf, 1},
+		{nil, inf, 1},
+	}
+	for _, tt := range tests {
+		if got := (LikelihoodRanker{}).Rank(c, tt.words); math.Abs(got-tt.want) > 1e-9 && got != tt.want {
+			t.Errorf("Rank(%v) = %g, want %g", tt.words, got, tt.want)
+		}
+		want := tt.want / float64(tt.steps)
+		if got := (LikelihoodRanker{Normalize: true}).Rank(c, tt.words); math.Abs(got-want) > 1e-9 && got != want {
+			t.Errorf("normalized Rank(%v) = %g, want %g", tt.words, got, want)
+		}
+	}
+}
+
+func TestNoveltyRanker(t *testing.T) {
+	r := NewNoveltyRanker(2)
+	r.Add("Кот спит на диване.")
+	tests := []struct {
+		words []string
+		want  float64
+	}{
+		{[]string{"кот", "спит", "на", "диване"}, 0},
+		{[]string{"кот", "спит", "долго"}, 0.5},
+		{[]string{"пёс", "спит", "во", "дворе"}, 1},
+		{[]string{"кот"}, 0},
+	}
+	for _, tt := range tests {
+		if got := r.Rank(nil, tt.words); got != tt.want {
+			t.Errorf("Rank(%v) = %g, want %g", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestKeywordRanker(t *testing.T) {
+	tests := []struct {
+		keywords []string
+		want     float64
+	}{
+		{[]string{"кот"}, 1},
+		{[]string{" Кот ", "пёс"}, 0.5},
+		{[]string{"пёс", "", " "}, 0},
+		{[]string{"", " "}, 0},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		if got := (KeywordRanker{Keywords: tt.keywords}).Rank(nil, []string{"кот", "спит"}); got != tt.want {
+			t.Errorf("Rank() with keywords %q = %g, want %g", tt.keywords, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateBest(t *testing.T) {
+	c := rankChain(t)
+	r := LengthRanker{Target: 3}
+	const n = 8
+
+	// candidates use the following seeds, the first best one wins
+	var want Candidate
+	for i := int64(0); i < n; i++ {
+		cand, err := c.GenerateWith(GenerateOptions{Seed: 1 + i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score := r.Rank(c, cand.Words); i == 0 || score > want.Score {
+			want, want.Score = cand, score
+		}
+	}
+	got, err := c.GenerateWith(GenerateOptions{Candidates: n, Ranker: r, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Words, want.Words) || got.Score != want.Score {
+		t.Errorf("GenerateWith() = %v, want %v", got, want)
+	}
+
+	var calls int32
+	counter := RankerFunc(func(c *Chain, words []string) float64 {
+		atomic.AddInt32(&calls, 1)
+		return float64(len(words))
+	})
+	best, err := c.GenerateBest(n, counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != n || best.Score != float64(len(best.Words)) {
+		t.Errorf("GenerateBest() = %v after %d calls, want score of %d ranked candidates", best, calls, n)
+	}
+}
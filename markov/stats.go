@@ -0,0 +1,122 @@
+package markov
+
+import (
+	"sort"
+)
+
+// Stats describes contents of the chain.
+type Stats struct {
+	// Vocabulary is amount of distinct words
+	Vocabulary int
+	// States is amount of cores which have following cells
+	States int
+	// Transitions is total amount of added cells
+	Transitions uint64
+	// Edges is amount of distinct core to cell pairs
+	Edges int
+	// Branching maps amount of following cells to amount of states having it
+	Branching map[int]int
+	// TopWords are the most frequent words
+	TopWords []WordCount
+	// TopTransitions are the most frequent transitions
+	TopTransitions []TransitionCount
+	// Sentences is amount of parsed sentences
+	Sentences uint64
+	// AvgSentenceLength is average amount of words in sentence
+	AvgSentenceLength float64
+	// DeadEnds is amount of transitions leading to cores which can't be continued
+	DeadEnds int
+}
+
+// WordCount is a word with its frequency.
+type WordCount struct {
+	Word  string
+	Count uint64
+}
+
+// TransitionCount is a transition with its frequency.
+type TransitionCount struct {
+	From  string
+	To    string
+	Count uint64
+}
+
+// Walk calls fn for each core and its cells in alphabetical order of cores
+// until fn returns false. Cells are copied, so fn may keep them.
+func (c *Chain) Walk(fn func(core string, cells []Cell) bool) {
+	cores := make([]string, 0, len(c.d))
+	for k := range c.d {
+		cores = append(cores, k)
+	}
+	sort.Strings(cores)
+	for _, k := range cores {
+		cells := make([]Cell, len(c.d[k]))
+		copy(cells, c.d[k])
+		if !fn(k, cells) {
+			return
+		}
+	}
+}
+
+// Stats collects statistics of the chain. top limits amount of
+// returned frequent words and transitions.
+func (c *Chain) Stats(top int) Stats {
+	s := Stats{
+		States:      len(c.d),
+		Transitions: c.totalRecords,
+		Branching:   make(map[int]int),
+	}
+	words := make(map[string]uint64)
+	transitions := make([]TransitionCount, 0)
+	var total uint64
+	for core, cells := range c.d {
+		s.Branching[len(cells)]++
+		s.Edges += len(cells)
+		for _, cell := range cells {
+			transitions = append(transitions, TransitionCount{core, cell.word, cell.count})
+			switch cell.ctype {
+			case Word:
+				words[cell.word] += cell.count
+				total += cell.count
+				if _, ok := c.d[c.nextCore(core, cell.word)]; !ok {
+					s.DeadEnds++
+				}
+			case End:
+				s.Sentences += cell.count
+			}
+		}
+	}
+	s.Vocabulary = len(words)
+	if s.Sentences > 0 {
+		s.AvgSentenceLength = float64(total) / float64(s.Sentences)
+	}
+
+	s.TopWords = make([]WordCount, 0, len(words))
+	for w, cnt := range words {
+		s.TopWords = append(s.TopWords, WordCount{w, cnt})
+	}
+	sort.Slice(s.TopWords, func(i, j int) bool {
+		if s.TopWords[i].Count != s.TopWords[j].Count {
+			return s.TopWords[i].Count > s.TopWords[j].Count
+		}
+		return s.TopWords[i].Word < s.TopWords[j].Word
+	})
+	sort.Slice(transitions, func(i, j int) bool {
+		ti, tj := transitions[i], transitions[j]
+		if ti.Count != tj.Count {
+			return ti.Count > tj.Count
+		}
+		if ti.From != tj.From {
+			return ti.From < tj.From
+		}
+		return ti.To < tj.To
+	})
+	if top >= 0 && len(s.TopWords) > top {
+		s.TopWords = s.TopWords[:top]
+	}
+	if top >= 0 && len(transitions) > top {
+		transitions = transitions[:top]
+	}
+	s.TopTransitions = transitions
+	return s
+}
@@ -0,0 +1,83 @@
+package markov
+
+import (
+	"reflect"
+	"testing"
+)
+
+// statsChain returns chain with fixed cells, c is a dead end.
+func statsChain(t *testing.T) *Chain {
+	c := NewChain()
+	for _, tr := range []struct {
+		core, word string
+		ctype      CellType
+		times      int
+	}{
+		{"*START*", "a", Word, 2},
+		{"*START*", "b", Word, 1},
+		{"a", "b", Word, 2},
+		{"a", "*END*", End, 1},
+		{"b", "*END*", End, 2},
+		{"b", "c", Word, 1},
+	} {
+		for i := 0; i < tr.times; i++ {
+			if err := c.AddCell(tr.core, NewCell(tr.word, 1, tr.ctype)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	c.CalculateCells()
+	return c
+}
+
+func TestStats(t *testing.T) {
+	s := statsChain(t).Stats(2)
+	want := Stats{
+		Vocabulary:        3,
+		States:            3,
+		Transitions:       9,
+		Edges:             6,
+		Branching:         map[int]int{2: 3},
+		TopWords:          []WordCount{{"b", 3}, {"a", 2}},
+		TopTransitions:    []TransitionCount{{"*START*", "a", 2}, {"a", "b", 2}},
+		Sentences:         3,
+		AvgSentenceLength: 2,
+		DeadEnds:          1,
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("Stats(2) = %+v, want %+v", s, want)
+	}
+
+	all := statsChain(t).Stats(-1)
+	if len(all.TopWords) != 3 || len(all.TopTransitions) != 6 {
+		t.Errorf("Stats(-1) has %d words and %d transitions, want all 3 and 6", len(all.TopWords), len(all.TopTransitions))
+	}
+	if want := (TransitionCount{"b", "*END*", 2}); all.TopTransitions[2] != want {
+		t.Errorf("third transition = %v, want %v", all.TopTransitions[2], want)
+	}
+	none := statsChain(t).Stats(0)
+	if len(none.TopWords) != 0 || len(none.TopTransitions) != 0 {
+		t.Errorf("Stats(0) = %+v, want no top words and transitions", none)
+	}
+
+	empty := NewChain().Stats(5)
+	if empty.Vocabulary != 0 || empty.AvgSentenceLength != 0 || len(empty.Branching) != 0 {
+		t.Errorf("Stats() of empty chain = %+v", empty)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	c := statsChain(t)
+	cores := make([]string, 0)
+	c.Walk(func(core string, cells []Cell) bool {
+		cores = append(cores, core)
+		cells[0].word = "changed"
+		return core != "a"
+	})
+	if want := []string{"*START*", "a"}; !reflect.DeepEqual(cores, want) {
+		t.Errorf("walked %v, want %v", cores, want)
+	}
+	if cells, _ := c.GetCells("*START*"); cells[0].GetWord() != "a" {
+		t.Errorf("Walk exposed cells of the chain, first word is %q", cells[0].GetWord())
+	}
+}
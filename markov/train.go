@@ -0,0 +1,151 @@
+package markov
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Text is a text to train chain on.
+type Text struct {
+	Text string
+	// Weight of each transition of the text, 1 if zero.
+	Weight float64
+}
+
+// TrainOptions tunes parallel training.
+type TrainOptions struct {
+	// Workers is amount of goroutines building chains, GOMAXPROCS if zero.
+	Workers int
+	// Order of the chain, 1 if zero.
+	Order int
+	// KeepCase disables lowercasing of words.
+	KeepCase bool
+	// Prepare is called by workers before parsing the text.
+	// Returning false skips the text.
+	Prepare func(text string) (string, bool)
+	// OnPanic is deferred by each worker, e.g. to report panics. Optional.
+	OnPanic func()
+}
+
+// TrainStats describes finished training.
+type TrainStats struct {
+	Workers  int
+	Texts    uint64
+	Skipped  uint64
+	Failed   uint64
+	Bytes    uint64
+	Parsing  time.Duration
+	Merging  time.Duration
+	Duration time.Duration
+}
+
+// TextsPerSecond returns throughput of training.
+func (s TrainStats) TextsPerSecond() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Texts) / s.Duration.Seconds()
+}
+
+// BytesPerSecond returns throughput of training.
+func (s TrainStats) BytesPerSecond() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Duration.Seconds()
+}
+
+// Train builds chain of texts in parallel: each worker fills its own chain
+// and all of them are merged when texts channel is closed. Chances of the
+// resulting chain are calculated.
+func Train(texts <-chan Text, opts TrainOptions) (*Chain, TrainStats) {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	stats := TrainStats{Workers: workers}
+	start := time.Now()
+
+	chains := make([]*Chain, workers)
+	var wg sync.WaitGroup
+	for i := range chains {
+		chains[i] = NewChainOrder(opts.Order)
+		chains[i].SetKeepCase(opts.KeepCase)
+		wg.Add(1)
+		go func(c *Chain) {
+			defer wg.Done()
+			if opts.OnPanic != nil {
+				defer opts.OnPanic()
+			}
+			for t := range texts {
+				text := t.Text
+				if opts.Prepare != nil {
+					var ok bool
+					if text, ok = opts.Prepare(text); !ok {
+						atomic.AddUint64(&stats.Skipped, 1)
+						continue
+					}
+				}
+				w := t.Weight
+				if w == 0 {
+					w = 1
+				}
+				if err := c.ParseTextWeighted(text, w); err != nil {
+					atomic.AddUint64(&stats.Failed, 1)
+					continue
+				}
+				atomic.AddUint64(&stats.Texts, 1)
+				atomic.AddUint64(&stats.Bytes, uint64(len(text)))
+			}
+		}(chains[i])
+	}
+	wg.Wait()
+	stats.Parsing = time.Since(start)
+
+	merged := chains[0]
+	for _, c := range chains[1:] {
+		// chains of workers are alike, so merging can't fail
+		_ = merged.Merge(c)
+	}
+	merged.CalculateCells()
+	stats.Duration = time.Since(start)
+	stats.Merging = stats.Duration - stats.Parsing
+	return merged, stats
+}
+
+// Merge adds all cells of other chain to the chain. Chains must have
+// the same order and case handling, ErrIncompatible is returned otherwise.
+// Chances must be recalculated afterwards.
+func (c *Chain) Merge(other *Chain) error {
+	if c.order != other.order || c.keepCase != other.keepCase {
+		return fmt.Errorf("%w: order %d and %d, keep case %t and %t",
+			ErrIncompatible, c.order, other.order, c.keepCase, other.keepCase)
+	}
+	for core, cells := range other.d {
+		for _, cell := range cells {
+			c.mergeCell(core, cell)
+		}
+	}
+	c.totalRecords += other.totalRecords
+	c.docs += other.docs
+	for w, n := range other.df {
+		c.df[w] += n
+	}
+	return nil
+}
+
+// mergeCell adds cell keeping its count and weight.
+func (c *Chain) mergeCell(core string, cell Cell) {
+	cell.chance = 0
+	for i := range c.d[core] {
+		if c.d[core][i].word == cell.word {
+			c.d[core][i].count += cell.count
+			c.d[core][i].weight += cell.weight
+			return
+		}
+	}
+	c.d[core] = append(c.d[core], cell)
+}
@@ -0,0 +1,121 @@
+package markov
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// trainCorpus returns texts with repeating words for training benchmarks.
+func trainCorpus(n int) []string {
+	words := []string{"кот", "спит", "на", "клавиатуре", "и", "пишет", "код", "весь", "день", "ночь"}
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("%s %s %s %s %s %s.",
+			words[i%10], words[(i/3)%10], words[(i/7)%10], words[(i/11)%10], words[(i/13)%10], words[(i/17)%10])
+	}
+	return texts
+}
+
+func trainTexts(texts []string) <-chan Text {
+	c := make(chan Text, len(texts))
+	for _, t := range texts {
+		c <- Text{Text: t}
+	}
+	close(c)
+	return c
+}
+
+func TestTrainMatchesSequential(t *testing.T) {
+	texts := trainCorpus(500)
+	seq := NewChainOrder(2)
+	for _, text := range texts {
+		if err := seq.ParseText(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seq.CalculateCells()
+	par, stats := Train(trainTexts(texts), TrainOptions{Workers: 4, Order: 2})
+	if stats.Texts != uint64(len(texts)) || stats.Workers != 4 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if par.GetTotalRecords() != seq.GetTotalRecords() || par.Documents() != seq.Documents() {
+		t.Errorf("records %d, documents %d, want %d, %d",
+			par.GetTotalRecords(), par.Documents(), seq.GetTotalRecords(), seq.Documents())
+	}
+	counts := func(c *Chain) map[string]uint64 {
+		m := make(map[string]uint64)
+		c.Walk(func(core string, cells []Cell) bool {
+			for _, cell := range cells {
+				m[core+" -> "+cell.word] = cell.count
+			}
+			return true
+		})
+		return m
+	}
+	if !reflect.DeepEqual(counts(par), counts(seq)) {
+		t.Error("parallel training differs from sequential one")
+	}
+}
+
+func TestMergeIncompatible(t *testing.T) {
+	tests := []struct {
+		name  string
+		other *Chain
+	}{
+		{"order", NewChainOrder(2)},
+		{"keep case", func() *Chain { c := NewChain(); c.SetKeepCase(true); return c }()},
+	}
+	for _, tt := range tests {
+		if err := NewChain().Merge(tt.other); !errors.Is(err, ErrIncompatible) {
+			t.Errorf("%s: Merge error = %v, want ErrIncompatible", tt.name, err)
+		}
+	}
+	if err := NewChain().Merge(NewChain()); err != nil {
+		t.Errorf("Merge of alike chains: %v", err)
+	}
+}
+
+func BenchmarkTrain(b *testing.B) {
+	texts := trainCorpus(20000)
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c := NewChain()
+			for _, text := range texts {
+				_ = c.ParseText(text)
+			}
+			c.CalculateCells()
+		}
+	})
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Train(trainTexts(texts), TrainOptions{Workers: workers})
+			}
+		})
+	}
+}
+
+func TestTrainOnPanic(t *testing.T) {
+	var recovered int32
+	c, stats := Train(trainTexts(trainCorpus(10)), TrainOptions{
+		Workers: 2,
+		Prepare: func(text string) (string, bool) {
+			if strings.HasPrefix(text, "кот") {
+				panic("prepare failed")
+			}
+			return text, true
+		},
+		OnPanic: func() {
+			if v := recover(); v != nil {
+				atomic.AddInt32(&recovered, 1)
+			}
+		},
+	})
+	if c == nil || atomic.LoadInt32(&recovered) == 0 {
+		t.Fatalf("panic of worker isn't handled, stats %+v", stats)
+	}
+}
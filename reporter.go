@@ -0,0 +1,177 @@
+package phrasegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/airbrake/gobrake"
+)
+
+// ErrorReporter sends errors and panics to some storage for later analysis.
+type ErrorReporter interface {
+	// Report sends the error with optional params.
+	Report(err error, params map[string]interface{})
+	// ReportPanic reports recovered panic and panics again.
+	// It must be deferred.
+	ReportPanic()
+	// Close sends pending reports and releases resources.
+	Close() error
+}
+
+// NewErrorReporter returns reporter chosen by configuration: "errbit",
+// "file", "none" or "auto" (errbit if it's configured, none otherwise).
+func NewErrorReporter(c Configuration) (ErrorReporter, error) {
+	switch c.Errors.Reporter {
+	case "errbit":
+		return NewAirbrakeReporter(c.Errbit, c.Environment), nil
+	case "file":
+		return NewFileReporter(c.Errors.File, c.Environment)
+	case "none":
+		return NopReporter{}, nil
+	case "auto", "":
+		if c.Errbit.configured() {
+			return NewAirbrakeReporter(c.Errbit, c.Environment), nil
+		}
+		return NopReporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown error reporter %q", c.Errors.Reporter)
+	}
+}
+
+// NopReporter drops all reports.
+type NopReporter struct{}
+
+// Report does nothing.
+func (NopReporter) Report(error, map[string]interface{}) {}
+
+// ReportPanic does nothing, panic goes on.
+func (NopReporter) ReportPanic() {}
+
+// Close does nothing.
+func (NopReporter) Close() error { return nil }
+
+// AirbrakeReporter sends reports to Airbrake or Errbit.
+type AirbrakeReporter struct {
+	n *gobrake.Notifier
+}
+
+// NewAirbrakeReporter creates reporter sending notices to the host.
+func NewAirbrakeReporter(c ErrbitConfig, env string) *AirbrakeReporter {
+	n := gobrake.NewNotifierWithOptions(&gobrake.NotifierOptions{
+		Host:        c.Host,
+		ProjectId:   c.ID,
+		ProjectKey:  c.Key,
+		Environment: env,
+		Revision:    Revision,
+	})
+	n.AddFilter(func(n *gobrake.Notice) *gobrake.Notice {
+		if n.Params == nil {
+			n.Params = make(map[string]interface{})
+		}
+		n.Params["version"] = Version
+		n.Params["revision"] = Revision
+		n.Params["environment"] = env
+		return n
+	})
+	return &AirbrakeReporter{n: n}
+}
+
+// Report sends notice asynchronously.
+func (r *AirbrakeReporter) Report(err error, params map[string]interface{}) {
+	notice := r.n.Notice(err, nil, 1)
+	for k, v := range params {
+		notice.Params[k] = v
+	}
+	r.n.SendNoticeAsync(notice)
+}
+
+// ReportPanic sends notice about the panic synchronously.
+func (r *AirbrakeReporter) ReportPanic() {
+	if v := recover(); v != nil {
+		_, _ = r.n.SendNotice(r.n.Notice(v, nil, 3))
+		panic(v)
+	}
+}
+
+// Close waits for pending notices.
+func (r *AirbrakeReporter) Close() error {
+	return r.n.Close()
+}
+
+// FileReporter appends reports to the file as JSON lines.
+type FileReporter struct {
+	mu  sync.Mutex
+	f   *os.File
+	env string
+}
+
+// fileReport is a single line of FileReporter.
+type fileReport struct {
+	Time        time.Time              `json:"time"`
+	Error       string                 `json:"error"`
+	Type        string                 `json:"type"`
+	Panic       bool                   `json:"panic,omitempty"`
+	Stack       string                 `json:"stack,omitempty"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+	Environment string                 `json:"environment"`
+	Version     string                 `json:"version"`
+	Revision    string                 `json:"revision"`
+}
+
+// NewFileReporter opens the file for appending reports.
+func NewFileReporter(path, env string) (*FileReporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open report file: %w", err)
+	}
+	return &FileReporter{f: f, env: env}, nil
+}
+
+// Report writes the error to the file.
+func (r *FileReporter) Report(err error, params map[string]interface{}) {
+	r.write(fileReport{
+		Error:  err.Error(),
+		Type:   fmt.Sprintf("%T", err),
+		Params: params,
+	})
+}
+
+// ReportPanic writes the panic with the stack to the file.
+func (r *FileReporter) ReportPanic() {
+	if v := recover(); v != nil {
+		r.write(fileReport{
+			Error: fmt.Sprint(v),
+			Type:  fmt.Sprintf("%T", v),
+			Panic: true,
+			Stack: string(debug.Stack()),
+		})
+		panic(v)
+	}
+}
+
+func (r *FileReporter) write(rep fileReport) {
+	rep.Time = time.Now()
+	rep.Environment = r.env
+	rep.Version = Version
+	rep.Revision = Revision
+	data, err := json.Marshal(rep)
+	if err != nil {
+		// params may be unserializable, the error itself is more important
+		rep.Params = nil
+		data, _ = json.Marshal(rep)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, _ = r.f.Write(append(data, '\n'))
+}
+
+// Close closes the file.
+func (r *FileReporter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
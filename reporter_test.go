@@ -0,0 +1,198 @@
+package phrasegen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// tempReportFile returns path to report file in a temporary directory.
+func tempReportFile(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "reports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "reports.jsonl")
+}
+
+// readReports returns reports written by FileReporter.
+func readReports(t *testing.T, path string) []fileReport {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := make([]fileReport, 0)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var r fileReport
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("invalid report line %q: %v", line, err)
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+// reportPanic calls f reporting its panic by r and returns the panic
+// which went on.
+func reportPanic(r ErrorReporter, f func()) (v interface{}) {
+	defer func() {
+		v = recover()
+	}()
+	defer r.ReportPanic()
+	f()
+	return nil
+}
+
+func TestNewErrorReporter(t *testing.T) {
+	errbit := ErrbitConfig{Host: "http://localhost", ID: 1, Key: "key"}
+	tests := []struct {
+		reporter string
+		errbit   ErrbitConfig
+		file     string
+		want     string
+	}{
+		{"auto", ErrbitConfig{}, "", "phrasegen.NopReporter"},
+		{"", ErrbitConfig{}, "", "phrasegen.NopReporter"},
+		{"auto", ErrbitConfig{Host: "http://localhost"}, "", "phrasegen.NopReporter"},
+		{"auto", errbit, "", "*phrasegen.AirbrakeReporter"},
+		{"errbit", errbit, "", "*phrasegen.AirbrakeReporter"},
+		{"file", ErrbitConfig{}, tempReportFile(t), "*phrasegen.FileReporter"},
+		{"none", errbit, "", "phrasegen.NopReporter"},
+		{"file", ErrbitConfig{}, filepath.Join(tempReportFile(t), "missing", "reports.jsonl"), ""},
+		{"sentry", ErrbitConfig{}, "", ""},
+	}
+	for _, tt := range tests {
+		c := DefaultConfiguration()
+		c.Errors.Reporter, c.Errors.File, c.Errbit = tt.reporter, tt.file, tt.errbit
+		r, err := NewErrorReporter(c)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("NewErrorReporter(%q, %q) must fail", tt.reporter, tt.file)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewErrorReporter(%q) failed: %v", tt.reporter, err)
+			continue
+		}
+		if got := fmt.Sprintf("%T", r); got != tt.want {
+			t.Errorf("NewErrorReporter(%q, %+v) = %s, want %s", tt.reporter, tt.errbit, got, tt.want)
+		}
+		if err := r.Close(); err != nil {
+			t.Errorf("Close() of %s failed: %v", tt.want, err)
+		}
+	}
+}
+
+func TestFileReporter(t *testing.T) {
+	path := tempReportFile(t)
+	r, err := NewFileReporter(path, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Report(errors.New("can't read"), map[string]interface{}{"model": "default"})
+	// params which can't be encoded are dropped
+	r.Report(errors.New("can't encode"), map[string]interface{}{"f": func() {}})
+	if v := reportPanic(r, func() { panic("boom") }); v != "boom" {
+		t.Errorf("panic %v didn't go on", v)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := readReports(t, path)
+	if len(reports) != 3 {
+		t.Fatalf("got %d reports, want 3", len(reports))
+	}
+	first := reports[0]
+	if first.Error != "can't read" || first.Type != "*errors.errorString" || first.Panic ||
+		first.Params["model"] != "default" || first.Environment != "test" || first.Version != Version || first.Time.IsZero() {
+		t.Errorf("report = %+v", first)
+	}
+	if reports[1].Error != "can't encode" || reports[1].Params != nil {
+		t.Errorf("report of unserializable params = %+v", reports[1])
+	}
+	p := reports[2]
+	if p.Error != "boom" || p.Type != "string" || !p.Panic || !strings.Contains(p.Stack, "reporter_test.go") {
+		t.Errorf("panic report = %+v", p)
+	}
+
+	// reports are appended
+	r, err = NewFileReporter(path, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Report(errors.New("again"), nil)
+	r.Close()
+	if n := len(readReports(t, path)); n != 4 {
+		t.Errorf("got %d reports after reopening, want 4", n)
+	}
+}
+
+func TestNopReporter(t *testing.T) {
+	r := NopReporter{}
+	r.Report(errors.New("dropped"), nil)
+	if v := reportPanic(r, func() { panic("boom") }); v != "boom" {
+		t.Errorf("panic %v didn't go on", v)
+	}
+	if v := reportPanic(r, func() {}); v != nil {
+		t.Errorf("panic %v without panicking", v)
+	}
+}
+
+func TestAirbrakeReporter(t *testing.T) {
+	var mu sync.Mutex
+	notices := make([]map[string]interface{}, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var notice map[string]interface{}
+		_ = json.NewDecoder(req.Body).Decode(&notice)
+		mu.Lock()
+		notices = append(notices, notice)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": "1"}`))
+	}))
+	defer srv.Close()
+
+	r := NewAirbrakeReporter(ErrbitConfig{Host: srv.URL, ID: 1, Key: "key"}, "test")
+	r.Report(errors.New("can't read"), map[string]interface{}{"model": "default"})
+	if v := reportPanic(r, func() { panic("boom") }); v != "boom" {
+		t.Errorf("panic %v didn't go on", v)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(notices) != 2 {
+		t.Fatalf("got %d notices, want 2", len(notices))
+	}
+	messages := make(map[string]map[string]interface{})
+	for _, n := range notices {
+		errs, _ := n["errors"].([]interface{})
+		if len(errs) == 0 {
+			t.Fatalf("notice without errors: %v", n)
+		}
+		msg, _ := errs[0].(map[string]interface{})["message"].(string)
+		params, _ := n["params"].(map[string]interface{})
+		messages[msg] = params
+	}
+	if p := messages["can't read"]; p["model"] != "default" || p["environment"] != "test" || p["version"] != Version {
+		t.Errorf("params of error notice = %v", p)
+	}
+	if _, ok := messages["boom"]; !ok {
+		t.Errorf("no notice of panic in %v", messages)
+	}
+}
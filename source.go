@@ -0,0 +1,71 @@
+package phrasegen
+
+import (
+	"context"
+
+	"github.com/ferux/phraseGen/markov"
+	"github.com/ferux/phraseGen/utils"
+)
+
+// Source provides texts to train on.
+type Source interface {
+	// Texts starts reading texts. Both channels are closed when source
+	// is exhausted or context is canceled.
+	Texts(ctx context.Context) (<-chan markov.Text, <-chan error)
+}
+
+// BashSource reads quotes of bash.im dump.
+type BashSource struct {
+	Parser *utils.BashParser
+	// Weight of the quote, quotes of zero weight are skipped.
+	// Every quote weights 1 if it's nil.
+	Weight utils.RatingWeight
+}
+
+// Texts starts the parser and sends weighted quotes.
+func (s *BashSource) Texts(ctx context.Context) (<-chan markov.Text, <-chan error) {
+	quotec, errc := s.Parser.StartQuotes()
+	texts := make(chan markov.Text, 100)
+	go func() {
+		defer close(texts)
+		for q := range quotec {
+			w := 1.0
+			if s.Weight != nil {
+				w = s.Weight(&q)
+			}
+			if w <= 0 {
+				continue
+			}
+			select {
+			case texts <- markov.Text{Text: q.Text, Weight: w}:
+			case <-ctx.Done():
+				// let the parser finish
+				for range quotec {
+				}
+				return
+			}
+		}
+	}()
+	return texts, errc
+}
+
+// TextSource provides fixed texts of weight 1.
+type TextSource []string
+
+// Texts sends the texts.
+func (s TextSource) Texts(ctx context.Context) (<-chan markov.Text, <-chan error) {
+	texts := make(chan markov.Text)
+	errc := make(chan error)
+	go func() {
+		defer close(texts)
+		defer close(errc)
+		for _, t := range s {
+			select {
+			case texts <- markov.Text{Text: t, Weight: 1}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return texts, errc
+}
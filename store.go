@@ -0,0 +1,47 @@
+package phrasegen
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultGenerator is a name of generator trained on the configured corpus.
+const DefaultGenerator = "default"
+
+// Store keeps trained generators by name. It's safe for concurrent use.
+type Store struct {
+	mu   sync.RWMutex
+	gens map[string]Generator
+}
+
+// NewStore creates empty store.
+func NewStore() *Store {
+	return &Store{gens: make(map[string]Generator)}
+}
+
+// Get returns generator by name.
+func (s *Store) Get(name string) (Generator, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	g, ok := s.gens[name]
+	return g, ok
+}
+
+// Put stores generator replacing the one of the same name.
+func (s *Store) Put(name string, g Generator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gens[name] = g
+}
+
+// Names returns sorted names of stored generators.
+func (s *Store) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.gens))
+	for name := range s.gens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
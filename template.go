@@ -0,0 +1,117 @@
+package phrasegen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Slot is a placeholder of template like {name} or {name:key=value,key=value}.
+type Slot struct {
+	Name   string
+	Params map[string]string
+}
+
+// Template is a text with slots. Braces are escaped by doubling: {{ and }}.
+type Template struct {
+	src   string
+	parts []templatePart
+}
+
+// templatePart is either a text or a slot.
+type templatePart struct {
+	text string
+	slot *Slot
+}
+
+// ParseTemplate parses template text.
+func ParseTemplate(s string) (*Template, error) {
+	t := &Template{src: s}
+	text := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"), strings.HasPrefix(s[i:], "}}"):
+			text.WriteByte(s[i])
+			i++
+		case s[i] == '}':
+			return nil, fmt.Errorf("template %q: unexpected } at %d", s, i)
+		case s[i] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("template %q: unclosed { at %d", s, i)
+			}
+			slot, err := parseSlot(s[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("template %q: %w", s, err)
+			}
+			if text.Len() > 0 {
+				t.parts = append(t.parts, templatePart{text: text.String()})
+				text.Reset()
+			}
+			t.parts = append(t.parts, templatePart{slot: slot})
+			i += end
+		default:
+			text.WriteByte(s[i])
+		}
+	}
+	if text.Len() > 0 {
+		t.parts = append(t.parts, templatePart{text: text.String()})
+	}
+	return t, nil
+}
+
+// parseSlot parses slot without braces.
+func parseSlot(s string) (*Slot, error) {
+	name, params := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		name, params = s[:i], s[i+1:]
+	}
+	slot := &Slot{Name: strings.TrimSpace(name), Params: make(map[string]string)}
+	if slot.Name == "" {
+		return nil, fmt.Errorf("slot %q has no name", s)
+	}
+	for _, p := range strings.Split(params, ",") {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("slot %q: parameter %q must be key=value", s, p)
+		}
+		slot.Params[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return slot, nil
+}
+
+// Slots returns slots of the template in order of appearance.
+func (t *Template) Slots() []Slot {
+	slots := make([]Slot, 0)
+	for _, p := range t.parts {
+		if p.slot != nil {
+			slots = append(slots, *p.slot)
+		}
+	}
+	return slots
+}
+
+// String returns source of the template.
+func (t *Template) String() string {
+	return t.src
+}
+
+// Execute fills slots of the template by fill.
+func (t *Template) Execute(ctx context.Context, fill func(ctx context.Context, s Slot) (string, error)) (string, error) {
+	b := &strings.Builder{}
+	for _, p := range t.parts {
+		if p.slot == nil {
+			b.WriteString(p.text)
+			continue
+		}
+		v, err := fill(ctx, *p.slot)
+		if err != nil {
+			return "", fmt.Errorf("slot %s: %w", p.slot.Name, err)
+		}
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,86 @@
+package phrasegen
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		in    string
+		out   string
+		slots []Slot
+	}{
+		{"plain text", "plain text", []Slot{}},
+		{"{{literal}} }}", "{literal} }", []Slot{}},
+		{"<{nick}> {phrase}", "<[nick]> [phrase]", []Slot{
+			{Name: "nick", Params: map[string]string{}},
+			{Name: "phrase", Params: map[string]string{}},
+		}},
+		{"{phrase: start = cat , max=6,}", "[phrase]", []Slot{
+			{Name: "phrase", Params: map[string]string{"start": "cat", "max": "6"}},
+		}},
+		{"{{{word}}}", "{[word]}", []Slot{{Name: "word", Params: map[string]string{}}}},
+	}
+	fill := func(ctx context.Context, s Slot) (string, error) {
+		return "[" + s.Name + "]", nil
+	}
+	for _, tt := range tests {
+		tpl, err := ParseTemplate(tt.in)
+		if err != nil {
+			t.Errorf("ParseTemplate(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got := tpl.Slots(); !reflect.DeepEqual(got, tt.slots) {
+			t.Errorf("ParseTemplate(%q) slots = %v, want %v", tt.in, got, tt.slots)
+		}
+		if got, err := tpl.Execute(context.Background(), fill); err != nil || got != tt.out {
+			t.Errorf("Execute(%q) = %q, %v, want %q", tt.in, got, err, tt.out)
+		}
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"text {phrase", "unclosed {"},
+		{"text } more", "unexpected }"},
+		{"{}", "has no name"},
+		{"{:max=3}", "has no name"},
+		{"{phrase:max}", "must be key=value"},
+	}
+	for _, tt := range tests {
+		_, err := ParseTemplate(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseTemplate(%q) error = %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestCheckSlots(t *testing.T) {
+	tests := []struct {
+		in string
+		ok bool
+	}{
+		{"{phrase:start=cat,min=2,max=6} {word:min=3} {nick2:max=8}", true},
+		{"{word:min=4,max=4}", true},
+		{"{unknown}", false},
+		{"{word:start=cat}", false},
+		{"{phrase:max=0}", false},
+		{"{nick:min=x}", false},
+		{"{word:min=8,max=3}", false},
+	}
+	for _, tt := range tests {
+		tpl, err := ParseTemplate(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkSlots(tpl); (err == nil) != tt.ok {
+			t.Errorf("checkSlots(%q) = %v, want ok %v", tt.in, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package phrasegen
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ferux/phraseGen/utils"
+)
+
+func init() {
+	RegisterBackend("template", newTemplateGenerator)
+}
+
+// nickSlotRegex matches names of nickname slots: nick, nick2 and so on.
+var nickSlotRegex = regexp.MustCompile(`^nick\d*$`)
+
+// slotParams lists parameters known by each slot.
+var slotParams = map[string][]string{
+	"phrase": {"start", "min", "max"},
+	"word":   {"min", "max"},
+	"nick":   {"min", "max"},
+}
+
+// templateGenerator fills templates by phrases of word chain and by
+// words and nicknames of character chain learned from its vocabulary.
+// Slots are:
+//
+//	{phrase:start=word,min=N,max=N} phrase with optional first word and length,
+//	    without the final period
+//	{word:min=N,max=N} invented word
+//	{nick}, {nick2}, ... invented nickname, the same within a single phrase
+type templateGenerator struct {
+	opts      Options
+	templates []*Template
+	words     *chainGenerator
+	filler    *utils.PlaceholderFiller
+
+	mu    sync.RWMutex
+	chars *charGenerator
+
+	rmu sync.Mutex
+	rnd *rand.Rand
+}
+
+// newTemplateGenerator creates generator of Options.Templates.
+func newTemplateGenerator(opts Options) (Generator, error) {
+	if len(opts.Templates) == 0 {
+		return nil, errors.New("template backend requires templates")
+	}
+	g := &templateGenerator{
+		opts:   opts,
+		filler: utils.NewPlaceholderFiller(),
+		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, s := range opts.Templates {
+		t, err := ParseTemplate(s)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSlots(t); err != nil {
+			return nil, fmt.Errorf("template %q: %w", s, err)
+		}
+		g.templates = append(g.templates, t)
+	}
+	words, err := newChainGenerator(opts)
+	if err != nil {
+		return nil, err
+	}
+	g.words = words.(*chainGenerator)
+	if err := g.resetChars(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// checkSlots ensures slots and their parameters are known.
+func checkSlots(t *Template) error {
+	for _, s := range t.Slots() {
+		name := s.Name
+		if nickSlotRegex.MatchString(name) {
+			name = "nick"
+		}
+		known, ok := slotParams[name]
+		if !ok {
+			return fmt.Errorf("unknown slot %q", s.Name)
+		}
+		for k, v := range s.Params {
+			if !oneOf(k, known...) {
+				return fmt.Errorf("slot %s: unknown parameter %q", s.Name, k)
+			}
+			if k == "min" || k == "max" {
+				if n, err := strconv.Atoi(v); err != nil || n < 1 {
+					return fmt.Errorf("slot %s: %s must be positive number, got %q", s.Name, k, v)
+				}
+			}
+		}
+		min, _ := strconv.Atoi(s.Params["min"])
+		if max, err := strconv.Atoi(s.Params["max"]); err == nil && min > max {
+			return fmt.Errorf("slot %s: min %d is greater than max %d", s.Name, min, max)
+		}
+	}
+	return nil
+}
+
+// resetChars learns character chain of the word chain vocabulary.
+func (g *templateGenerator) resetChars() error {
+	chars, err := newCharGenerator(Options{CharOrder: g.opts.CharOrder, Logger: g.opts.Logger})
+	if err != nil {
+		return err
+	}
+	cg := chars.(*charGenerator)
+	if err := cg.LearnVocabulary(g.words.Chain()); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	g.chars = cg
+	g.mu.Unlock()
+	return nil
+}
+
+// Train learns the source by word chain and its vocabulary by character chain.
+func (g *templateGenerator) Train(ctx context.Context, src Source) error {
+	err := g.words.Train(ctx, src)
+	if ctx.Err() != nil {
+		return err
+	}
+	if rerr := g.resetChars(); rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+// Generate fills random template.
+func (g *templateGenerator) Generate(ctx context.Context, opts GenerateOptions) (Phrase, error) {
+	return g.execute(ctx, "", opts)
+}
+
+// Reply fills random template with phrases replying to the message
+// unless the slot sets the first word.
+func (g *templateGenerator) Reply(ctx context.Context, message string, opts GenerateOptions) (Phrase, error) {
+	return g.execute(ctx, message, opts)
+}
+
+// execute fills random template. Phrases reply to the message if it isn't empty.
+func (g *templateGenerator) execute(ctx context.Context, message string, opts GenerateOptions) (Phrase, error) {
+	g.rmu.Lock()
+	t := g.templates[g.rnd.Intn(len(g.templates))]
+	g.rmu.Unlock()
+	g.mu.RLock()
+	chars := g.chars
+	g.mu.RUnlock()
+
+	nicks := make(map[string]string)
+	var keywords []string
+	text, err := t.Execute(ctx, func(ctx context.Context, s Slot) (string, error) {
+		switch {
+		case s.Name == "phrase":
+			p, err := g.phrase(ctx, s, message, opts)
+			keywords = append(keywords, p.Keywords...)
+			// punctuation is up to the template
+			return strings.TrimSuffix(p.Text, "."), err
+		case s.Name == "word":
+			return g.word(ctx, chars, s, opts)
+		default:
+			if nick, ok := nicks[s.Name]; ok {
+				return nick, nil
+			}
+			nick := g.nick(ctx, chars, s, opts, nicks)
+			nicks[s.Name] = nick
+			return nick, nil
+		}
+	})
+	if err != nil {
+		return Phrase{}, err
+	}
+	return Phrase{Text: text, Words: strings.Fields(text), Keywords: keywords}, nil
+}
+
+// phrase fills phrase slot by word chain.
+func (g *templateGenerator) phrase(ctx context.Context, s Slot, message string, opts GenerateOptions) (Phrase, error) {
+	if v, ok := s.Params["start"]; ok {
+		opts.Start = v
+		message = ""
+	}
+	if v, ok := s.Params["min"]; ok {
+		opts.MinWords, _ = strconv.Atoi(v)
+	}
+	if v, ok := s.Params["max"]; ok {
+		opts.MaxWords, _ = strconv.Atoi(v)
+	}
+	if message != "" {
+		return g.words.Reply(ctx, message, opts)
+	}
+	return g.words.Generate(ctx, opts)
+}
+
+// word fills word slot by character chain.
+func (g *templateGenerator) word(ctx context.Context, chars *charGenerator, s Slot, opts GenerateOptions) (string, error) {
+	if v, ok := s.Params["min"]; ok {
+		opts.MinLength, _ = strconv.Atoi(v)
+	}
+	if v, ok := s.Params["max"]; ok {
+		opts.MaxLength, _ = strconv.Atoi(v)
+	}
+	opts.RejectKnown = true
+	opts.Accept = nil
+	p, err := chars.Generate(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	return p.Text, nil
+}
+
+// nickRetries is amount of nicknames invented to find unused one.
+const nickRetries = 10
+
+// nick invents capitalized nickname differing from the used ones. Built-in
+// nicknames are used if the character chain can't invent one.
+func (g *templateGenerator) nick(ctx context.Context, chars *charGenerator, s Slot, opts GenerateOptions, used map[string]string) string {
+	opts.MinLength, opts.MaxLength = 3, 10
+	// attempts are shared by all retries
+	if opts.Attempts < 1 {
+		opts.Attempts = defaultCharAttempts
+	}
+	if opts.Attempts /= nickRetries; opts.Attempts < 1 {
+		opts.Attempts = 1
+	}
+	for i := 0; i < nickRetries; i++ {
+		w, err := g.word(ctx, chars, s, opts)
+		if err != nil || w == "" {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(w)
+		nick := string(unicode.ToUpper(r)) + w[size:]
+		if !containsValue(used, nick) {
+			return nick
+		}
+	}
+	return g.filler.Fill(utils.PlaceholderNick)
+}
+
+// Save writes the word chain. Character chain is learned again on load.
+func (g *templateGenerator) Save(w io.Writer) error {
+	return g.words.Save(w)
+}
+
+// Load replaces the word chain and learns its vocabulary.
+func (g *templateGenerator) Load(r io.Reader) error {
+	if err := g.words.Load(r); err != nil {
+		return err
+	}
+	return g.resetChars()
+}
+
+// LoadTemplates reads templates one per line. Blank lines and lines
+// starting with # are skipped, \n is replaced by new line.
+func LoadTemplates(r io.Reader) ([]string, error) {
+	templates := make([]string, 0)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		templates = append(templates, strings.Replace(line, `\n`, "\n", -1))
+	}
+	return templates, sc.Err()
+}
+
+func containsValue(m map[string]string, v string) bool {
+	for _, mv := range m {
+		if mv == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package phrasegen
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTemplateGenerator(t *testing.T) {
+	ctx := context.Background()
+	g, err := New(Options{
+		Backend:   "template",
+		Workers:   1,
+		CharOrder: 2,
+		Templates: []string{"{nick}: {phrase:start=я}\n{nick2}: {phrase:max=5}? {nick}!"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := TextSource{
+		"я люблю собак.",
+		"собака лает громко.",
+		"я слышу как собака лает.",
+		"кошка спит весь день.",
+	}
+	if err := g.Train(ctx, src); err != nil {
+		t.Fatal(err)
+	}
+	re := regexp.MustCompile(`^(\S+): (я [^.?!\n]+)\n(\S+): ([^.?!\n]+)\? (\S+)!$`)
+	for i := 0; i < 10; i++ {
+		p, err := g.Generate(ctx, GenerateOptions{MaxLength: 8})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := re.FindStringSubmatch(p.Text)
+		if m == nil {
+			t.Fatalf("Generate() = %q doesn't match the template", p.Text)
+		}
+		if m[1] != m[5] {
+			t.Errorf("Generate() = %q, the same slot got nicknames %q and %q", p.Text, m[1], m[5])
+		}
+		if n := len(strings.Fields(m[4])); n > 5 {
+			t.Errorf("Generate() = %q, second phrase has %d words, want at most 5", p.Text, n)
+		}
+		if strings.Join(p.Words, " ") != strings.Join(strings.Fields(p.Text), " ") {
+			t.Errorf("Generate() words %q don't match text %q", p.Words, p.Text)
+		}
+	}
+
+	p, err := g.(Replier).Reply(ctx, "кошка", GenerateOptions{})
+	if err != nil || !strings.Contains(p.Text, "кошка") {
+		t.Errorf("Reply() = %q, %v, want phrase with кошка", p.Text, err)
+	}
+}
@@ -2,7 +2,7 @@ package utils
 
 import (
 	"encoding/json"
-	"fmt"
+	"html"
 	"io/ioutil"
 	"os"
 	"regexp"
@@ -12,10 +12,36 @@ import (
 )
 
 // BashStruct describes model of the parsed bash.im quotes.
+// Date is zero if it's missing or has unknown format.
 type BashStruct struct {
-	Date   time.Time `json:"-"`
-	Number string    `json:"-"`
+	Date   time.Time `json:"date"`
+	Number int64     `json:"number"`
 	Text   string    `json:"text"`
+	// Rating is nil if quote has no rating
+	Rating *int64 `json:"rating,omitempty"`
+}
+
+// UnmarshalJSON tolerates numbers written as strings like "#123"
+// and dates in various formats.
+func (b *BashStruct) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Date   json.RawMessage `json:"date"`
+		Number json.RawMessage `json:"number"`
+		Text   string          `json:"text"`
+		Rating json.RawMessage `json:"rating"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*b = BashStruct{Text: raw.Text}
+	b.Number, _ = parseNumber(raw.Number)
+	b.Date, _ = parseRawDate(raw.Date)
+	if len(raw.Rating) > 0 && string(raw.Rating) != "null" {
+		if r, err := parseNumber(raw.Rating); err == nil {
+			b.Rating = &r
+		}
+	}
+	return nil
 }
 
 // GetText returns text of quote.
@@ -30,10 +56,19 @@ type BashParser struct {
 	fast     bool
 
 	bashQuotes []BashStruct
+	filter     QuoteFilter
+	progress   Progress
 
-	outc chan string
-	errc chan error
-	done chan struct{}
+	// scrub and block are stages applied to quotes before sending
+	scrub Stage
+	block Stage
+	// onPanic is deferred by goroutines of the parser
+	onPanic func()
+
+	outc   chan string
+	quotec chan BashStruct
+	errc   chan error
+	done   chan struct{}
 
 	l *logrus.Entry
 }
@@ -54,10 +89,72 @@ func NewBashParser(filename string, loglevel logrus.Level) *BashParser {
 		}),
 	}
 	bp.l.Level = loglevel
+	bp.progress = NewProgress(bp.l)
 
 	return bp
 }
 
+// SetLogger sets logger of the parser.
+func (b *BashParser) SetLogger(l *logrus.Entry) {
+	b.l = l.WithFields(logrus.Fields{
+		"pkg": "utils",
+		"obj": "BashParser",
+	})
+}
+
+// SetFilter sets filter of quotes to send.
+func (b *BashParser) SetFilter(f QuoteFilter) {
+	b.filter = f
+}
+
+// SetBlocklist sets blocklist applied to each quote before sending it.
+// It's a shortcut for BlocklistStage applied by the parser, nil disables it.
+func (b *BashParser) SetBlocklist(bl *Blocklist, mode BlockMode) {
+	b.block = nil
+	if bl != nil {
+		b.block = BlocklistStage(bl, mode)
+	}
+}
+
+// SetScrubPII enables replacing personal data in quotes with placeholders.
+// It's a shortcut for PIIStage applied by the parser before blocklist.
+func (b *BashParser) SetScrubPII(on bool) {
+	b.scrub = nil
+	if on {
+		b.scrub = PIIStage()
+	}
+}
+
+// prepare applies stages set by SetScrubPII and SetBlocklist to the quote.
+// It returns false if quote must be skipped.
+func (b *BashParser) prepare(q *BashStruct) bool {
+	for _, st := range []Stage{b.scrub, b.block} {
+		if st == nil {
+			continue
+		}
+		text, ok := st.Process(q.Text)
+		if !ok {
+			return false
+		}
+		q.Text = text
+	}
+	return true
+}
+
+// SetPanicHandler sets function deferred by goroutines of the parser,
+// e.g. to report panics. Nil disables it.
+func (b *BashParser) SetPanicHandler(h func()) {
+	b.onPanic = h
+}
+
+// SetProgress sets reporter of parsing progress. Nil disables reporting.
+func (b *BashParser) SetProgress(p Progress) {
+	if p == nil {
+		p = NopProgress{}
+	}
+	b.progress = p
+}
+
 // GetChannel returns channel to get strings for parsing
 func (b *BashParser) GetChannel() <-chan string {
 	return b.outc
@@ -70,15 +167,31 @@ func (b *BashParser) Close() {
 
 // Start creates channels and runs loop for processing file.
 func (b *BashParser) Start() (<-chan string, <-chan error) {
-
+	quotec, errc := b.StartQuotes()
 	b.outc = make(chan string, 100)
+	go func() {
+		defer close(b.outc)
+		if b.onPanic != nil {
+			defer b.onPanic()
+		}
+		for q := range quotec {
+			b.outc <- q.Text
+		}
+	}()
+	return b.outc, errc
+}
+
+// StartQuotes creates channels and runs loop for processing file.
+// Unlike Start it sends whole quotes.
+func (b *BashParser) StartQuotes() (<-chan BashStruct, <-chan error) {
+	b.quotec = make(chan BashStruct, 100)
 	b.errc = make(chan error, 100)
 	if b.fast {
 		go b.fastloop()
 	} else {
 		go b.loop()
 	}
-	return b.outc, b.errc
+	return b.quotec, b.errc
 }
 
 func (b *BashParser) loop() {
@@ -88,6 +201,9 @@ func (b *BashParser) loop() {
 		"file": b.filename,
 	})
 	l.Info("Started loop")
+	if b.onPanic != nil {
+		defer b.onPanic()
+	}
 
 	// open file
 	f, err := os.Open(b.filename)
@@ -101,7 +217,7 @@ func (b *BashParser) loop() {
 			l.WithError(err).Error("can't close file")
 		}
 		l.Info("Finished loop")
-		close(b.outc)
+		close(b.quotec)
 		close(b.errc)
 		close(b.done)
 	}()
@@ -113,25 +229,31 @@ func (b *BashParser) loop() {
 		return
 	}
 	var quote BashStruct
-	var rows uint32
+	var rows int64
+	var size int64
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
 	start := time.Now()
-	spent := ""
-	go func() {
-		for range time.Tick(time.Second) {
-			spent = time.Since(start).String()
-		}
-	}()
+	b.progress.Start(0, size)
 	for dec.More() {
 		if err := dec.Decode(&quote); err != nil {
 			l.WithError(err).Error("can't decode row, skipping")
 			b.errc <- err
 			continue
 		}
-		b.outc <- quote.Text
 		rows++
-		fmt.Print("\033[2K\r")
-		fmt.Printf("Processing row: %d (%s)", rows, spent)
+		if !b.filter.Match(&quote) {
+			continue
+		}
+		quote.Text = cleanBashHTML(quote.Text)
+		if !b.prepare(&quote) {
+			continue
+		}
+		b.quotec <- quote
+		b.progress.Update(rows, dec.InputOffset())
 	}
+	b.progress.Finish()
 
 	if _, err := dec.Token(); err != nil {
 		l.WithError(err).Error("can't extract close token")
@@ -149,10 +271,13 @@ func (b *BashParser) fastloop() {
 	l.Info("Started loop")
 
 	defer func() {
-		close(b.outc)
+		close(b.quotec)
 		close(b.errc)
 		close(b.done)
 	}()
+	if b.onPanic != nil {
+		defer b.onPanic()
+	}
 	// open file
 	f, err := ioutil.ReadFile(b.filename)
 	if err != nil {
@@ -170,21 +295,50 @@ func (b *BashParser) fastloop() {
 		l.WithError(err).Error("can't unmarshal")
 		b.errc <- err
 	}
-	var rows uint64
+	var rows int64
 	start := time.Now()
-	t := time.NewTicker(time.Second / 2)
-	go func() {
-		for range t.C {
-			fmt.Print("\033[2K\r")
-			fmt.Print("Processing row: ", rows)
-		}
-	}()
-	for _, bq := range bqs {
-		b.outc <- filterBashDialog(bq.Text)
+	b.progress.Start(int64(len(bqs)), 0)
+	var skipped uint64
+	for i := range bqs {
 		rows++
+		b.progress.Update(rows, 0)
+		if !b.filter.Match(&bqs[i]) {
+			skipped++
+			continue
+		}
+		bqs[i].Text = cleanBashHTML(bqs[i].Text)
+		if !b.prepare(&bqs[i]) {
+			skipped++
+			continue
+		}
+		b.quotec <- bqs[i]
 	}
-	t.Stop()
-	l.Infof("rows proceeded: %d for %s", rows, time.Since(start).String())
+	b.progress.Finish()
+	l.Infof("rows proceeded: %d (%d filtered out) for %s", rows, skipped, time.Since(start).String())
+}
+
+// tagRegex finds common HTML tags. Nicknames like <nick> don't match it
+// unless they are named like a tag.
+var tagRegex = regexp.MustCompile(`(?i)</?(?:a|b|i|u|s|p|em|strong|span|div|font|small|big|pre|code|sup|sub)(?:\s[^<>]*)?/?>`)
+
+var (
+	// doubleEntityRegex finds entities encoded twice like &amp;quot;.
+	doubleEntityRegex = regexp.MustCompile(`&amp;((?:[a-zA-Z]+|#[0-9]+|#[xX][0-9a-fA-F]+);)`)
+	// encodedBreakRegex finds encoded <br> tags which some quotes have
+	// instead of real ones.
+	encodedBreakRegex = regexp.MustCompile(`(?i)&lt;br\s*/?&gt;`)
+)
+
+// cleanBashHTML converts line breaks to new lines, strips tags and decodes
+// HTML entities of scraped quote. Entities are decoded once after the tags
+// are stripped, so tags typed in the quote itself survive as text.
+func cleanBashHTML(s string) string {
+	// some quotes are encoded twice, e.g. &amp;quot; or &lt;br&gt;
+	s = doubleEntityRegex.ReplaceAllString(s, "&$1")
+	s = breakRegex.ReplaceAllString(s, "\n")
+	s = encodedBreakRegex.ReplaceAllString(s, "\n")
+	s = tagRegex.ReplaceAllString(s, "")
+	return html.UnescapeString(s)
 }
 
 var filterBashDialogsRegex = regexp.MustCompile(`(?m)^([\w\d]+:\s*)(.+)$`)
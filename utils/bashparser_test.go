@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// parseQuotes writes the JSON corpus to a temporary file and returns texts
+// sent by the parser.
+func parseQuotes(t *testing.T, corpus string, setup func(b *BashParser)) []string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "bashparser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "quotes.json")
+	if err := ioutil.WriteFile(fn, []byte(corpus), 0600); err != nil {
+		t.Fatal(err)
+	}
+	b := NewBashParser(fn, logrus.PanicLevel)
+	log := logrus.New()
+	log.Level = logrus.PanicLevel
+	b.SetLogger(logrus.NewEntry(log))
+	b.SetProgress(nil)
+	if setup != nil {
+		setup(b)
+	}
+	quotec, errc := b.StartQuotes()
+	texts := make([]string, 0)
+	for q := range quotec {
+		texts = append(texts, q.Text)
+	}
+	for err := range errc {
+		t.Fatal(err)
+	}
+	return texts
+}
+
+func TestBashParserStages(t *testing.T) {
+	corpus := `[
+		{"number": 1, "text": "<Vasya> привет, дурак"},
+		{"number": 2, "text": "пиши на vasya@mail.ru"},
+		{"number": 3, "text": "просто текст"}
+	]`
+	tests := []struct {
+		name  string
+		setup func(b *BashParser)
+		want  []string
+	}{
+		{"none", nil, []string{"<Vasya> привет, дурак", "пиши на vasya@mail.ru", "просто текст"}},
+		{"pii", func(b *BashParser) { b.SetScrubPII(true) }, []string{"<NICK> привет, дурак", "пиши на <EMAIL>", "просто текст"}},
+		{"pii off", func(b *BashParser) { b.SetScrubPII(true); b.SetScrubPII(false) }, []string{"<Vasya> привет, дурак", "пиши на vasya@mail.ru", "просто текст"}},
+		{"blocklist", func(b *BashParser) { b.SetBlocklist(NewBlocklist("дурак"), BlockText) }, []string{"пиши на vasya@mail.ru", "просто текст"}},
+		{"both", func(b *BashParser) {
+			b.SetBlocklist(NewBlocklist("дурак"), BlockMask)
+			b.SetScrubPII(true)
+		}, []string{"<NICK> привет, д****", "пиши на <EMAIL>", "просто текст"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseQuotes(t, corpus, tt.setup); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("texts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanBashHTML(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "просто текст", "просто текст"},
+		{"break", "&lt;Vasya&gt; привет<br>&lt;Petya&gt; пока", "<Vasya> привет\n<Petya> пока"},
+		{"break variants", "раз<br/>два<BR />три", "раз\nдва\nтри"},
+		{"encoded break", "xxx: ну и?&lt;br&gt;yyy: и всё", "xxx: ну и?\nyyy: и всё"},
+		{"entities", "&quot;цитата&quot; &#39;да&#39; &amp; нет", `"цитата" 'да' & нет`},
+		{"double quote", "он сказал &amp;quot;нет&amp;quot;", `он сказал "нет"`},
+		{"double break", "раз&amp;lt;br&amp;gt;два", "раз\nдва"},
+		{"markup", `<b>жирный</b> и <a href="http://bash.im">ссылка</a>`, "жирный и ссылка"},
+		{"typed tag", "пишите &lt;b&gt;жирным&lt;/b&gt;", "пишите <b>жирным</b>"},
+		{"double typed tag", "пишите &amp;lt;b&amp;gt;жирным", "пишите <b>жирным"},
+		{"nick like tag", "&lt;font&gt; я шрифт", "<font> я шрифт"},
+		{"typed entity", "пишите &amp;amp; вместо &amp;", "пишите & вместо &"},
+		{"unknown tag kept", "<xxx> привет", "<xxx> привет"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanBashHTML(tt.in); got != tt.want {
+				t.Errorf("cleanBashHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBashParserCleansHTML(t *testing.T) {
+	corpus := `[
+		{"number": 412, "date": "2005-03-14 10:11", "rating": 1200,
+		 "text": "&lt;Ghostrider&gt; доброе утро&lt;br&gt;&lt;DarkMan&gt; &amp;quot;доброе&amp;quot;, ага"},
+		{"number": 413, "text": "<b>xxx:</b> я тут<br>yyy: &amp;lt;i&amp;gt; где?"}
+	]`
+	want := []string{
+		"<Ghostrider> доброе утро\n<DarkMan> \"доброе\", ага",
+		"xxx: я тут\nyyy: <i> где?",
+	}
+	if got := parseQuotes(t, corpus, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("texts = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// BlockMode describes what to do with text containing blocked words.
+type BlockMode int
+
+// Enums for BlockMode
+const (
+	// BlockOff leaves text as is
+	BlockOff BlockMode = iota
+	// BlockText drops the whole text
+	BlockText
+	// BlockWords drops blocked words only
+	BlockWords
+	// BlockMask replaces blocked words with asterisks
+	BlockMask
+)
+
+// ParseBlockMode converts mode name to BlockMode.
+func ParseBlockMode(s string) (BlockMode, bool) {
+	switch s {
+	case "", "off":
+		return BlockOff, true
+	case "text", "quote", "reject":
+		return BlockText, true
+	case "words":
+		return BlockWords, true
+	case "mask":
+		return BlockMask, true
+	}
+	return BlockOff, false
+}
+
+var (
+	// wordRegex finds words in any script.
+	wordRegex = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+	// lookalikes maps latin letters used to disguise cyrillic words.
+	lookalikes = strings.NewReplacer(
+		"a", "а", "e", "е", "o", "о", "p", "р", "c", "с",
+		"x", "х", "y", "у", "k", "к", "m", "м", "t", "т",
+		"h", "н", "b", "в", "ё", "е",
+	)
+
+	// ruSuffixes are common russian inflections, longest first.
+	ruSuffixes = []string{
+		"иями", "ями", "ами", "ого", "его", "ому", "ему", "ыми", "ими",
+		"ешь", "ишь", "ете", "ите", "ают", "яют", "уют", "ует", "ать",
+		"ять", "ить", "еть", "уть", "ют", "ут", "ат", "ят", "ой", "ей",
+		"ый", "ий", "ая", "яя", "ое", "ее", "ые", "ие", "ую", "юю", "ом",
+		"ем", "ам", "ям", "ах", "ях", "ов", "ев", "ла", "ли", "ло", "ть",
+		"а", "я", "о", "е", "ы", "и", "у", "ю", "ь", "й",
+	}
+)
+
+// Blocklist matches words against a list of banned words. Matching is
+// aware of russian inflections: "слово" blocks "слова" and "словами".
+// Entries ending with "*" block every word with such prefix.
+type Blocklist struct {
+	stems    map[string]struct{}
+	prefixes []string
+}
+
+// NewBlocklist creates blocklist of words.
+func NewBlocklist(words ...string) *Blocklist {
+	b := &Blocklist{stems: make(map[string]struct{})}
+	for _, w := range words {
+		b.Add(w)
+	}
+	return b
+}
+
+// LoadBlocklist reads blocklist from files containing one word per line.
+// Empty lines and lines starting with "#" are skipped.
+func LoadBlocklist(filenames ...string) (*Blocklist, error) {
+	b := NewBlocklist()
+	for _, fn := range filenames {
+		f, err := os.Open(fn)
+		if err != nil {
+			return nil, err
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if len(line) == 0 || line[0] == '#' {
+				continue
+			}
+			b.Add(line)
+		}
+		err = sc.Err()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Add adds word to the blocklist.
+func (b *Blocklist) Add(word string) {
+	word = normalizeWord(word)
+	if strings.HasSuffix(word, "*") {
+		if p := strings.TrimRight(word, "*"); len(p) > 0 {
+			b.prefixes = append(b.prefixes, p)
+		}
+		return
+	}
+	if len(word) > 0 {
+		b.stems[stemRu(word)] = struct{}{}
+	}
+}
+
+// Len returns amount of entries in the blocklist.
+func (b *Blocklist) Len() int {
+	return len(b.stems) + len(b.prefixes)
+}
+
+// Match reports whether the word is blocked.
+func (b *Blocklist) Match(word string) bool {
+	word = normalizeWord(word)
+	if _, ok := b.stems[stemRu(word)]; ok {
+		return true
+	}
+	for _, p := range b.prefixes {
+		if strings.HasPrefix(word, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether text has any blocked word.
+func (b *Blocklist) Contains(text string) bool {
+	for _, w := range wordRegex.FindAllString(text, -1) {
+		if b.Match(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mask replaces all letters of blocked words except the first one with "*".
+func (b *Blocklist) Mask(text string) string {
+	return wordRegex.ReplaceAllStringFunc(text, func(w string) string {
+		if !b.Match(w) {
+			return w
+		}
+		r, size := utf8.DecodeRuneInString(w)
+		return string(r) + strings.Repeat("*", utf8.RuneCountInString(w[size:]))
+	})
+}
+
+// Strip removes blocked words from text with spaces around them keeping
+// other separators including new lines.
+func (b *Blocklist) Strip(text string) string {
+	sb := &strings.Builder{}
+	last := 0
+	for _, loc := range wordRegex.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if start < last || !b.Match(text[start:end]) {
+			continue
+		}
+		next := end
+		for next < len(text) && isBlank(text[next]) {
+			next++
+		}
+		if next > end && next < len(text) && text[next] != '\n' && text[next] != '\r' {
+			end = next
+		} else {
+			end = next
+			for start > last && isBlank(text[start-1]) {
+				start--
+			}
+		}
+		sb.WriteString(text[last:start])
+		last = end
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// Apply filters text according to mode. It returns false if the text
+// must be dropped entirely.
+func (b *Blocklist) Apply(text string, mode BlockMode) (string, bool) {
+	switch mode {
+	case BlockText:
+		return text, !b.Contains(text)
+	case BlockWords:
+		return b.Strip(text), true
+	case BlockMask:
+		return b.Mask(text), true
+	}
+	return text, true
+}
+
+// normalizeWord lowercases the word and replaces latin lookalikes
+// if the word has cyrillic letters.
+func normalizeWord(w string) string {
+	w = strings.ToLower(strings.TrimSpace(w))
+	for _, r := range w {
+		if r >= 'а' && r <= 'я' || r == 'ё' {
+			return lookalikes.Replace(w)
+		}
+	}
+	return w
+}
+
+// stemRu strips the longest russian inflection keeping at least 3 letters.
+func stemRu(w string) string {
+	for _, refl := range []string{"ся", "сь"} {
+		if strings.HasSuffix(w, refl) && utf8.RuneCountInString(w) > 5 {
+			w = strings.TrimSuffix(w, refl)
+			break
+		}
+	}
+	for _, s := range ruSuffixes {
+		if strings.HasSuffix(w, s) && utf8.RuneCountInString(w)-utf8.RuneCountInString(s) >= 3 {
+			return strings.TrimSuffix(w, s)
+		}
+	}
+	return w
+}
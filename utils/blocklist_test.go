@@ -0,0 +1,91 @@
+package utils
+
+import "testing"
+
+func TestStemRu(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"слово", "слов"},
+		{"слова", "слов"},
+		{"словами", "слов"},
+		{"дурак", "дурак"},
+		{"дураками", "дурак"},
+		{"ругаться", "руг"},
+		{"кот", "кот"},
+		{"коты", "кот"},
+		{"word", "word"},
+	}
+	for _, tt := range tests {
+		if got := stemRu(tt.in); got != tt.want {
+			t.Errorf("stemRu(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeWord(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Слово", "слово"},
+		{"cлoвo", "слово"},
+		{"ДУPAK", "дурак"},
+		{"ёжик", "ежик"},
+		{"Cat", "cat"},
+	}
+	for _, tt := range tests {
+		if got := normalizeWord(tt.in); got != tt.want {
+			t.Errorf("normalizeWord(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBlocklistMatch(t *testing.T) {
+	b := NewBlocklist("дурак", "bad*", "# not a comment here")
+	tests := []struct {
+		word string
+		want bool
+	}{
+		{"дурак", true},
+		{"Дураками", true},
+		{"дурaк", true},
+		{"дуpакu", false},
+		{"badly", true},
+		{"BAD", true},
+		{"abad", false},
+		{"кот", false},
+	}
+	for _, tt := range tests {
+		if got := b.Match(tt.word); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestBlocklistApply(t *testing.T) {
+	b := NewBlocklist("дурак")
+	tests := []struct {
+		name string
+		text string
+		mode BlockMode
+		want string
+		keep bool
+	}{
+		{"off", "ты дурак", BlockOff, "ты дурак", true},
+		{"text", "ты дурак", BlockText, "ты дурак", false},
+		{"text clean", "ты кот", BlockText, "ты кот", true},
+		{"mask", "ты дурак!", BlockMask, "ты д****!", true},
+		{"words", "ты дурак, да", BlockWords, "ты, да", true},
+		{"words first", "Дурак ты", BlockWords, "ты", true},
+		{"words new lines", "<a> ты дурак\n<b> сам  дурак\n<a> нет", BlockWords, "<a> ты\n<b> сам\n<a> нет", true},
+		{"words keep spaces", "a  дурак b\tc", BlockWords, "a  b\tc", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, keep := b.Apply(tt.text, tt.mode)
+			if got != tt.want || keep != tt.keep {
+				t.Errorf("Apply(%q) = %q, %v, want %q, %v", tt.text, got, keep, tt.want, tt.keep)
+			}
+		})
+	}
+}
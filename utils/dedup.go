@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultDedupeThreshold is similarity of texts considered near duplicates.
+	DefaultDedupeThreshold = 0.8
+
+	// minhashSize is amount of hash functions in text signature.
+	minhashSize = 64
+	// shingleSize is amount of words in a shingle.
+	shingleSize = 3
+)
+
+// Duplicate describes removed text.
+type Duplicate struct {
+	Text       string
+	Original   string
+	Similarity float64
+	Exact      bool
+}
+
+// Deduplicator drops exact duplicates and near duplicates found by
+// MinHash of word shingles. It implements Stage named "dedupe".
+type Deduplicator struct {
+	threshold float64
+	bands     int
+	rows      int
+
+	mu      sync.Mutex
+	exact   map[uint64]int
+	buckets map[uint64][]int
+	kept    []string
+	sigs    [][minhashSize]uint64
+	removed []Duplicate
+}
+
+// NewDeduplicator creates deduplicator considering texts with
+// Jaccard similarity at least threshold as duplicates.
+// Threshold of 1 or more disables near duplicates detection.
+func NewDeduplicator(threshold float64) *Deduplicator {
+	d := &Deduplicator{
+		exact:   make(map[uint64]int),
+		buckets: make(map[uint64][]int),
+	}
+	d.SetThreshold(threshold)
+	return d
+}
+
+// SetThreshold changes similarity threshold. It mustn't be called
+// after processing started.
+func (d *Deduplicator) SetThreshold(threshold float64) {
+	d.threshold = threshold
+	// choose bands so that probability of becoming candidates
+	// rises steeply around the threshold: t ≈ (1/bands)^(1/rows)
+	best := math.Inf(1)
+	for rows := 1; rows <= minhashSize; rows++ {
+		if minhashSize%rows != 0 {
+			continue
+		}
+		bands := minhashSize / rows
+		t := math.Pow(1/float64(bands), 1/float64(rows))
+		if diff := math.Abs(t - threshold); diff < best {
+			best = diff
+			d.bands, d.rows = bands, rows
+		}
+	}
+}
+
+// Name returns name of the stage.
+func (d *Deduplicator) Name() string {
+	return "dedupe"
+}
+
+// Process drops text if it's a duplicate of already processed one.
+func (d *Deduplicator) Process(text string) (string, bool) {
+	if _, dup := d.Check(text); dup {
+		return text, false
+	}
+	return text, true
+}
+
+// Check reports whether text duplicates one of already checked texts.
+// Unique texts are remembered.
+func (d *Deduplicator) Check(text string) (Duplicate, bool) {
+	words := wordRegex.FindAllString(strings.ToLower(text), -1)
+	key := hashString(strings.Join(words, " "))
+	near := d.threshold < 1
+	var sig [minhashSize]uint64
+	if near {
+		sig = minhash(words)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if i, ok := d.exact[key]; ok {
+		dup := Duplicate{Text: text, Original: d.kept[i], Similarity: 1, Exact: true}
+		d.removed = append(d.removed, dup)
+		return dup, true
+	}
+
+	idx := len(d.kept)
+	if near {
+		bandKeys := make([]uint64, d.bands)
+		checked := make(map[int]struct{})
+		for b := 0; b < d.bands; b++ {
+			bandKeys[b] = hashBand(b, sig[b*d.rows:(b+1)*d.rows])
+			for _, i := range d.buckets[bandKeys[b]] {
+				if _, ok := checked[i]; ok {
+					continue
+				}
+				checked[i] = struct{}{}
+				if sim := similarity(&sig, &d.sigs[i]); sim >= d.threshold {
+					dup := Duplicate{Text: text, Original: d.kept[i], Similarity: sim}
+					d.removed = append(d.removed, dup)
+					return dup, true
+				}
+			}
+		}
+		for _, k := range bandKeys {
+			d.buckets[k] = append(d.buckets[k], idx)
+		}
+		d.sigs = append(d.sigs, sig)
+	}
+	d.exact[key] = idx
+	d.kept = append(d.kept, text)
+	return Duplicate{}, false
+}
+
+// Removed returns removed duplicates.
+func (d *Deduplicator) Removed() []Duplicate {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Duplicate(nil), d.removed...)
+}
+
+// WriteReport writes removed duplicates along with their originals.
+func (d *Deduplicator) WriteReport(w io.Writer) error {
+	removed := d.Removed()
+	d.mu.Lock()
+	unique := len(d.kept)
+	d.mu.Unlock()
+	var exact int
+	for _, dup := range removed {
+		if dup.Exact {
+			exact++
+		}
+	}
+	if _, err := fmt.Fprintf(w, "Removed %d duplicates (%d exact, %d near) of %d unique texts\n",
+		len(removed), exact, len(removed)-exact, unique); err != nil {
+		return err
+	}
+	for _, dup := range removed {
+		kind := "near"
+		if dup.Exact {
+			kind = "exact"
+		}
+		if _, err := fmt.Fprintf(w, "\n--- %s, similarity %.2f\n%s\n+++ original\n%s\n",
+			kind, dup.Similarity, dup.Text, dup.Original); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// minhash calculates signature of word shingles.
+func minhash(words []string) [minhashSize]uint64 {
+	var sig [minhashSize]uint64
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	n := shingleSize
+	if len(words) < n {
+		n = len(words)
+	}
+	for i := 0; i+n <= len(words); i++ {
+		h := hashString(strings.Join(words[i:i+n], " "))
+		for j := range sig {
+			if v := mix(h ^ uint64(j+1)*0x9e3779b97f4a7c15); v < sig[j] {
+				sig[j] = v
+			}
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return sig
+}
+
+// similarity estimates Jaccard similarity of texts by their signatures.
+func similarity(a, b *[minhashSize]uint64) float64 {
+	var eq int
+	for i := range a {
+		if a[i] == b[i] {
+			eq++
+		}
+	}
+	return float64(eq) / minhashSize
+}
+
+func hashBand(band int, rows []uint64) uint64 {
+	h := uint64(band) * 0x9e3779b97f4a7c15
+	for _, r := range rows {
+		h = mix(h ^ r)
+	}
+	return h
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// mix is a finalizer of splitmix64.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
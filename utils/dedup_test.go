@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMinhashSimilarity(t *testing.T) {
+	a := strings.Fields("раз два три четыре пять шесть семь восемь девять десять одиннадцать двенадцать")
+	same := minhash(a)
+	if sim := similarity(&same, &same); sim != 1 {
+		t.Errorf("similarity of equal signatures = %g, want 1", sim)
+	}
+	b := append(append([]string(nil), a...), "тринадцать")
+	sa, sb := minhash(a), minhash(b)
+	// 10 of 11 shingles are shared
+	if sim := similarity(&sa, &sb); math.Abs(sim-10.0/11) > 0.15 {
+		t.Errorf("similarity of near texts = %g, want about %g", sim, 10.0/11)
+	}
+	c := strings.Fields("совсем другой текст про котов и собак который ничем не похож")
+	sc := minhash(c)
+	if sim := similarity(&sa, &sc); sim > 0.1 {
+		t.Errorf("similarity of different texts = %g, want about 0", sim)
+	}
+}
+
+func TestMinhashShortText(t *testing.T) {
+	one, other := minhash([]string{"привет"}), minhash([]string{"привет"})
+	if similarity(&one, &other) != 1 {
+		t.Error("equal short texts must have equal signatures")
+	}
+}
+
+func TestSetThresholdBands(t *testing.T) {
+	for _, threshold := range []float64{0.5, 0.8, 0.9} {
+		d := NewDeduplicator(threshold)
+		if d.bands*d.rows != minhashSize {
+			t.Errorf("threshold %g: %d bands of %d rows don't cover signature", threshold, d.bands, d.rows)
+		}
+		if t0 := math.Pow(1/float64(d.bands), 1/float64(d.rows)); math.Abs(t0-threshold) > 0.15 {
+			t.Errorf("threshold %g: bands threshold %g is too far", threshold, t0)
+		}
+	}
+}
+
+func TestDeduplicator(t *testing.T) {
+	texts := []struct {
+		text string
+		dup  bool
+		near bool
+	}{
+		{"<NICK> я сегодня купил новый телефон и он сразу сломался прямо в руках", false, false},
+		{"<NICK> Я сегодня купил новый телефон, и он сразу сломался прямо в руках!", true, false},
+		{"<NICK> я сегодня купил новый телефон и он сразу сломался прямо в руках вот", true, true},
+		{"совсем другая цитата про кота который спит на клавиатуре весь день", false, false},
+		{"короткая", false, false},
+	}
+	d := NewDeduplicator(0.8)
+	for _, tt := range texts {
+		dup, found := d.Check(tt.text)
+		if found != tt.dup {
+			t.Errorf("Check(%q) = %v, want %v", tt.text, found, tt.dup)
+			continue
+		}
+		if found && dup.Exact == tt.near {
+			t.Errorf("Check(%q) exact = %v, want %v", tt.text, dup.Exact, !tt.near)
+		}
+		if found && dup.Original != texts[0].text {
+			t.Errorf("Check(%q) original = %q", tt.text, dup.Original)
+		}
+	}
+	if n := len(d.Removed()); n != 2 {
+		t.Errorf("removed %d texts, want 2", n)
+	}
+}
+
+func TestDeduplicatorExactOnly(t *testing.T) {
+	d := NewDeduplicator(1)
+	if _, ok := d.Process("раз два три четыре пять шесть"); !ok {
+		t.Fatal("first text dropped")
+	}
+	if _, ok := d.Process("раз два три четыре пять шесть семь"); !ok {
+		t.Error("near duplicate dropped with threshold 1")
+	}
+	if _, ok := d.Process("Раз, два, три, четыре, пять, шесть."); ok {
+		t.Error("exact duplicate kept")
+	}
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Placeholders replacing personal data in texts.
+const (
+	PlaceholderNick  = "<NICK>"
+	PlaceholderEmail = "<EMAIL>"
+	PlaceholderURL   = "<URL>"
+	PlaceholderIP    = "<IP>"
+	PlaceholderPhone = "<PHONE>"
+)
+
+type piiRule struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// piiRules are applied in order: nicknames at line start go first so they
+// don't catch placeholders, emails go before mentions and urls so they
+// aren't split.
+var piiRules = []piiRule{
+	{regexp.MustCompile(`(?m)^[ \t]*<[^<>\s]{1,32}>`), PlaceholderNick},
+	{regexp.MustCompile(`(?m)^([ \t]*\*[ \t]+)[^\s]+`), "$1" + PlaceholderNick},
+	{regexp.MustCompile(`[\w.+\-]+@[\w\-]+(\.[\w\-]+)+`), PlaceholderEmail},
+	{regexp.MustCompile(`@[\p{L}\p{N}_\-]+`), PlaceholderNick},
+	{regexp.MustCompile(`(?i)\b(?:(?:https?|ftp)://|www\.)[^\s<>"]*[^\s<>".,;:!?)]`), PlaceholderURL},
+	{regexp.MustCompile(`(?i)[\p{L}\p{N}\-]+(?:\.[\p{L}\p{N}\-]+)*\.(?:ru|su|com|net|org|info|im|рф)(/[^\s<>"]*)?($|[^\p{L}\p{N}])`), PlaceholderURL + "${2}"},
+	{regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), PlaceholderIP},
+	{regexp.MustCompile(`(?:\+7|\b8)[ \-]?\(?\d{3}\)?[ \-]?\d{3}[ \-]?\d{2}[ \-]?\d{2}\b`), PlaceholderPhone},
+	{regexp.MustCompile(`\+\d[\d \-()]{8,}\d`), PlaceholderPhone},
+}
+
+// ScrubPII replaces nicknames, emails, urls, ip addresses and phones
+// in text with placeholders.
+func ScrubPII(text string) string {
+	for _, r := range piiRules {
+		text = r.re.ReplaceAllString(text, r.repl)
+	}
+	return text
+}
+
+var placeholderRegex = regexp.MustCompile(`(?i)<(?:nick|email|url|ip|phone)>`)
+
+// PlaceholderFiller replaces placeholders in generated texts
+// with synthetic values.
+type PlaceholderFiller struct {
+	// Nick generates nicknames. Built-in generator is used if nil.
+	Nick func() string
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewPlaceholderFiller creates new filler.
+func NewPlaceholderFiller() *PlaceholderFiller {
+	return &PlaceholderFiller{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Fill replaces every placeholder in text.
+func (f *PlaceholderFiller) Fill(text string) string {
+	return placeholderRegex.ReplaceAllStringFunc(text, func(p string) string {
+		switch strings.ToUpper(p) {
+		case PlaceholderNick:
+			if f.Nick != nil {
+				return f.Nick()
+			}
+			return f.nick()
+		case PlaceholderEmail:
+			return fmt.Sprintf("%s@example.com", f.nick())
+		case PlaceholderURL:
+			return fmt.Sprintf("http://example.com/%d", f.intn(10000))
+		case PlaceholderIP:
+			// 192.0.2.0/24 is reserved for documentation
+			return fmt.Sprintf("192.0.2.%d", 1+f.intn(254))
+		case PlaceholderPhone:
+			return fmt.Sprintf("+7 (900) 555-%02d-%02d", f.intn(100), f.intn(100))
+		}
+		return p
+	})
+}
+
+var (
+	nickHeads = []string{"xxx", "yyy", "Vasya", "Petya", "Anon", "Gost", "Kot", "Ded", "Lamer", "Admin"}
+	nickTails = []string{"", "_", "Pupkin", "2000", "X", "_Killer", "Moroz", "ok"}
+)
+
+func (f *PlaceholderFiller) nick() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return nickHeads[f.rnd.Intn(len(nickHeads))] + nickTails[f.rnd.Intn(len(nickTails))]
+}
+
+func (f *PlaceholderFiller) intn(n int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rnd.Intn(n)
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScrubPII(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"nick", "<Vasya> привет", "<NICK> привет"},
+		{"action", "* Vasya ушел", "* <NICK> ушел"},
+		{"mention", "спроси @vasya_99 потом", "спроси <NICK> потом"},
+		{"email", "пиши на vasya.pupkin@mail.ru", "пиши на <EMAIL>"},
+		{"url", "смотри http://bash.im/quote/123.", "смотри <URL>."},
+		{"www", "www.example.com, там", "<URL>, там"},
+		{"domain", "зайди на bash.im", "зайди на <URL>"},
+		{"domain path", "зайди на bash.im/best и всё", "зайди на <URL> и всё"},
+		{"cyrillic domain", "зайди на сайт.рф!", "зайди на <URL>!"},
+		{"cyrillic subdomain", "мой-блог.пример.рф тут", "<URL> тут"},
+		{"two domains", "a.ru b.ru", "<URL> <URL>"},
+		{"not tld", "слово.russia и foo.comet", "слово.russia и foo.comet"},
+		{"ip", "мой ip 192.168.0.1 ага", "мой ip <IP> ага"},
+		{"phone", "звони 8 (916) 123-45-67", "звони <PHONE>"},
+		{"intl phone", "звони +44 20 7946 0958", "звони <PHONE>"},
+		{"plain", "обычный текст. без данных", "обычный текст. без данных"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScrubPII(tt.in); got != tt.want {
+				t.Errorf("ScrubPII(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaceholderFiller(t *testing.T) {
+	f := NewPlaceholderFiller()
+	f.Nick = func() string { return "Kot" }
+	got := f.Fill("<NICK> wrote to <email> from <URL>")
+	if strings.Contains(got, "<") {
+		t.Fatalf("placeholders left in %q", got)
+	}
+	if !strings.HasPrefix(got, "Kot wrote to ") || !strings.Contains(got, "@example.com from http://example.com/") {
+		t.Errorf("unexpected fill %q", got)
+	}
+}
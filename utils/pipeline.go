@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Stage is a single step of text preprocessing.
+type Stage interface {
+	// Name of the stage
+	Name() string
+	// Process transforms text. It returns false if text must be dropped.
+	Process(text string) (string, bool)
+}
+
+type stage struct {
+	name string
+	fn   func(string) (string, bool)
+}
+
+func (s stage) Name() string                       { return s.name }
+func (s stage) Process(text string) (string, bool) { return s.fn(text) }
+
+// NewStage creates stage from function.
+func NewStage(name string, fn func(text string) (string, bool)) Stage {
+	return stage{name, fn}
+}
+
+// StageCounter holds amount of texts passed through the stage.
+type StageCounter struct {
+	Name    string
+	In      uint64
+	Changed uint64
+	Dropped uint64
+}
+
+// Pipeline applies stages to texts one by one. It's safe for concurrent use
+// if its stages are.
+type Pipeline struct {
+	stages   []Stage
+	counters []StageCounter
+}
+
+// NewPipeline creates pipeline of stages.
+func NewPipeline(stages ...Stage) *Pipeline {
+	p := &Pipeline{}
+	for _, s := range stages {
+		p.Add(s)
+	}
+	return p
+}
+
+// ParsePipeline creates pipeline from comma-separated stage names.
+func ParsePipeline(spec string) (*Pipeline, error) {
+	p := NewPipeline()
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		s, err := StageByName(name)
+		if err != nil {
+			return nil, err
+		}
+		p.Add(s)
+	}
+	return p, nil
+}
+
+// Add appends stage to the pipeline. It mustn't be called while
+// pipeline is processing texts.
+func (p *Pipeline) Add(s Stage) {
+	p.stages = append(p.stages, s)
+	p.counters = append(p.counters, StageCounter{Name: s.Name()})
+}
+
+// Process passes text through all stages. It returns false if
+// one of stages dropped the text.
+func (p *Pipeline) Process(text string) (string, bool) {
+	for i, s := range p.stages {
+		c := &p.counters[i]
+		atomic.AddUint64(&c.In, 1)
+		out, ok := s.Process(text)
+		if !ok {
+			atomic.AddUint64(&c.Dropped, 1)
+			return "", false
+		}
+		if out != text {
+			atomic.AddUint64(&c.Changed, 1)
+		}
+		text = out
+	}
+	return text, true
+}
+
+// Run processes texts from in and sends the rest to returned channel
+// which is closed after in is closed.
+func (p *Pipeline) Run(in <-chan string) <-chan string {
+	out := make(chan string, cap(in))
+	go func() {
+		defer close(out)
+		for text := range in {
+			if text, ok := p.Process(text); ok {
+				out <- text
+			}
+		}
+	}()
+	return out
+}
+
+// Stages returns stages of the pipeline.
+func (p *Pipeline) Stages() []Stage {
+	return append([]Stage(nil), p.stages...)
+}
+
+// Counters returns snapshot of per-stage counters.
+func (p *Pipeline) Counters() []StageCounter {
+	cs := make([]StageCounter, len(p.counters))
+	for i := range p.counters {
+		cs[i] = StageCounter{
+			Name:    p.counters[i].Name,
+			In:      atomic.LoadUint64(&p.counters[i].In),
+			Changed: atomic.LoadUint64(&p.counters[i].Changed),
+			Dropped: atomic.LoadUint64(&p.counters[i].Dropped),
+		}
+	}
+	return cs
+}
+
+// stages maps names to constructors of built-in stages.
+var stages = map[string]func() Stage{
+	"html":      HTMLStage,
+	"br":        BreakStage,
+	"dialog":    DialogStage,
+	"normalize": NormalizeStage,
+	"lower":     LowerStage,
+	"pii":       PIIStage,
+	"dedupe":    DedupeStage,
+}
+
+// StageNames returns names of built-in stages accepted by StageByName.
+func StageNames() []string {
+	return []string{"html", "br", "dialog", "normalize", "lower", "pii", "dedupe"}
+}
+
+// StageByName creates built-in stage.
+func StageByName(name string) (Stage, error) {
+	if fn, ok := stages[name]; ok {
+		return fn(), nil
+	}
+	return nil, fmt.Errorf("unknown stage %q", name)
+}
+
+// HTMLStage decodes HTML entities like &quot;.
+func HTMLStage() Stage {
+	return NewStage("html", func(s string) (string, bool) {
+		return html.UnescapeString(s), true
+	})
+}
+
+var breakRegex = regexp.MustCompile(`(?i)<br\s*/?>`)
+
+// BreakStage replaces <br> tags with new lines.
+func BreakStage() Stage {
+	return NewStage("br", func(s string) (string, bool) {
+		return breakRegex.ReplaceAllString(s, "\n"), true
+	})
+}
+
+// DialogStage strips "nick:" prefixes of dialog lines.
+func DialogStage() Stage {
+	return NewStage("dialog", func(s string) (string, bool) {
+		return filterBashDialog(s), true
+	})
+}
+
+var (
+	normalizeReplacer = strings.NewReplacer(
+		"«", `"`, "»", `"`, "„", `"`, "“", `"`, "”", `"`,
+		"‘", "'", "’", "'", "—", "-", "–", "-", "…", "...",
+		" ", " ", "\r\n", "\n", "\r", "\n", "\t", " ",
+	)
+	spacesRegex = regexp.MustCompile(` {2,}`)
+)
+
+// NormalizeStage unifies quotes, dashes and spaces and drops empty lines.
+// Text which becomes empty is dropped.
+func NormalizeStage() Stage {
+	return NewStage("normalize", func(s string) (string, bool) {
+		s = spacesRegex.ReplaceAllString(normalizeReplacer.Replace(s), " ")
+		lines := strings.Split(s, "\n")
+		out := lines[:0]
+		for _, line := range lines {
+			if line = strings.TrimSpace(line); len(line) > 0 {
+				out = append(out, line)
+			}
+		}
+		s = strings.Join(out, "\n")
+		return s, len(s) > 0
+	})
+}
+
+// LowerStage lowercases text.
+func LowerStage() Stage {
+	return NewStage("lower", func(s string) (string, bool) {
+		return strings.ToLower(s), true
+	})
+}
+
+// PIIStage replaces personal data with placeholders.
+func PIIStage() Stage {
+	return NewStage("pii", func(s string) (string, bool) {
+		return ScrubPII(s), true
+	})
+}
+
+// DedupeStage drops exact and near duplicates with default threshold.
+func DedupeStage() Stage {
+	return NewDeduplicator(DefaultDedupeThreshold)
+}
+
+// BlocklistStage filters blocked words according to mode.
+func BlocklistStage(bl *Blocklist, mode BlockMode) Stage {
+	return NewStage("blocklist", func(s string) (string, bool) {
+		return bl.Apply(s, mode)
+	})
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStages(t *testing.T) {
+	tests := []struct {
+		stage  Stage
+		in     string
+		out    string
+		passed bool
+	}{
+		{BreakStage(), "one<br>two<BR/>three<br />four", "one\ntwo\nthree\nfour", true},
+		{BreakStage(), "no tags", "no tags", true},
+		{DialogStage(), "xxx: hello\nyyy:   bye", "hello\nbye", true},
+		{DialogStage(), "time is 10:30", "time is 10:30", true},
+		{NormalizeStage(), "«quote» — dash\t and  spaces", `"quote" - dash and spaces`, true},
+		{NormalizeStage(), "one\r\n\r\n  two  \n", "one\ntwo", true},
+		{NormalizeStage(), " \r\n\t\n ", "", false},
+		{NormalizeStage(), "", "", false},
+		{HTMLStage(), "&quot;hi&quot; &amp; bye", `"hi" & bye`, true},
+		{LowerStage(), "Hello World", "hello world", true},
+	}
+	for _, tt := range tests {
+		out, passed := tt.stage.Process(tt.in)
+		if out != tt.out || passed != tt.passed {
+			t.Errorf("%s stage of %q = %q, %t, want %q, %t",
+				tt.stage.Name(), tt.in, out, passed, tt.out, tt.passed)
+		}
+	}
+}
+
+func TestPipelineProcess(t *testing.T) {
+	p := NewPipeline(BreakStage(), DialogStage(), NormalizeStage(), LowerStage())
+	tests := []struct {
+		in     string
+		out    string
+		passed bool
+	}{
+		{"xxx: Hello<br>yyy: World", "hello\nworld", true},
+		{"Already clean", "already clean", true},
+		{"<br><br>", "", false},
+	}
+	for _, tt := range tests {
+		out, passed := p.Process(tt.in)
+		if out != tt.out || passed != tt.passed {
+			t.Errorf("Process(%q) = %q, %t, want %q, %t", tt.in, out, passed, tt.out, tt.passed)
+		}
+	}
+
+	// the last text is dropped by normalize, so lower never sees it
+	want := []StageCounter{
+		{Name: "br", In: 3, Changed: 2},
+		{Name: "dialog", In: 3, Changed: 1},
+		{Name: "normalize", In: 3, Dropped: 1},
+		{Name: "lower", In: 2, Changed: 2},
+	}
+	if got := p.Counters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Counters() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPipelineRun(t *testing.T) {
+	p := NewPipeline(NormalizeStage())
+	in := make(chan string, 3)
+	in <- " one "
+	in <- "  "
+	in <- "two"
+	close(in)
+	var got []string
+	for text := range p.Run(in) {
+		got = append(got, text)
+	}
+	if want := []string{"one", "two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Run() = %q, want %q", got, want)
+	}
+}
+
+func TestParsePipeline(t *testing.T) {
+	tests := []struct {
+		spec  string
+		names []string
+		err   string
+	}{
+		{"", nil, ""},
+		{"html, br,dialog,,normalize", []string{"html", "br", "dialog", "normalize"}, ""},
+		{strings.Join(StageNames(), ","), StageNames(), ""},
+		{"html,nope", nil, `unknown stage "nope"`},
+		{"HTML", nil, `unknown stage "HTML"`},
+	}
+	for _, tt := range tests {
+		p, err := ParsePipeline(tt.spec)
+		if len(tt.err) > 0 {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("ParsePipeline(%q) error = %v, want %s", tt.spec, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePipeline(%q) error = %v", tt.spec, err)
+			continue
+		}
+		var names []string
+		for _, s := range p.Stages() {
+			names = append(names, s.Name())
+		}
+		if !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("ParsePipeline(%q) stages = %q, want %q", tt.spec, names, tt.names)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Progress reports processing of a corpus. Methods are called
+// from a single goroutine.
+type Progress interface {
+	// Start is called before processing. Zero totals mean they're unknown.
+	Start(totalRows, totalBytes int64)
+	// Update reports amount of rows processed and bytes read so far.
+	Update(rows, bytes int64)
+	// Finish is called after processing.
+	Finish()
+}
+
+// NewProgress chooses progress bar if both stdout and stderr are
+// terminals, and log lines otherwise.
+func NewProgress(l *logrus.Entry) Progress {
+	if terminal.IsTerminal(int(os.Stdout.Fd())) && terminal.IsTerminal(int(os.Stderr.Fd())) {
+		return NewTTYProgress(os.Stderr)
+	}
+	return NewLogProgress(l, 5*time.Second)
+}
+
+// NopProgress reports nothing.
+type NopProgress struct{}
+
+// Start does nothing.
+func (NopProgress) Start(totalRows, totalBytes int64) {}
+
+// Update does nothing.
+func (NopProgress) Update(rows, bytes int64) {}
+
+// Finish does nothing.
+func (NopProgress) Finish() {}
+
+// progressState calculates rate and ETA of processing.
+type progressState struct {
+	totalRows  int64
+	totalBytes int64
+	rows       int64
+	bytes      int64
+	start      time.Time
+	last       time.Time
+}
+
+func (s *progressState) begin(totalRows, totalBytes int64) {
+	*s = progressState{totalRows: totalRows, totalBytes: totalBytes, start: time.Now()}
+}
+
+// due reports whether interval passed since last report.
+func (s *progressState) due(interval time.Duration) bool {
+	now := time.Now()
+	if now.Sub(s.last) < interval {
+		return false
+	}
+	s.last = now
+	return true
+}
+
+// rate returns rows per second.
+func (s *progressState) rate() float64 {
+	spent := time.Since(s.start).Seconds()
+	if spent <= 0 {
+		return 0
+	}
+	return float64(s.rows) / spent
+}
+
+// done returns processed share in range [0, 1] or -1 if it's unknown.
+func (s *progressState) done() float64 {
+	switch {
+	case s.totalBytes > 0 && s.bytes > 0:
+		return float64(s.bytes) / float64(s.totalBytes)
+	case s.totalRows > 0:
+		return float64(s.rows) / float64(s.totalRows)
+	}
+	return -1
+}
+
+// eta returns estimated time left or -1 if it's unknown.
+func (s *progressState) eta() time.Duration {
+	done := s.done()
+	if done <= 0 {
+		return -1
+	}
+	spent := time.Since(s.start)
+	return time.Duration(float64(spent)/done) - spent
+}
+
+func (s *progressState) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%d rows", s.rows)
+	if s.bytes > 0 {
+		fmt.Fprintf(b, ", %.1f MB", float64(s.bytes)/(1<<20))
+	}
+	fmt.Fprintf(b, ", %.0f rows/s", s.rate())
+	if eta := s.eta(); eta >= 0 {
+		fmt.Fprintf(b, ", ETA %s", eta.Round(time.Second))
+	}
+	return b.String()
+}
+
+// TTYProgress draws progress bar in terminal.
+type TTYProgress struct {
+	w     io.Writer
+	width int
+	s     progressState
+}
+
+// NewTTYProgress creates progress bar writing to w.
+func NewTTYProgress(w io.Writer) *TTYProgress {
+	return &TTYProgress{w: w, width: 30}
+}
+
+// Start resets the bar.
+func (p *TTYProgress) Start(totalRows, totalBytes int64) {
+	p.s.begin(totalRows, totalBytes)
+}
+
+// Update redraws the bar at most 10 times per second.
+func (p *TTYProgress) Update(rows, bytes int64) {
+	p.s.rows, p.s.bytes = rows, bytes
+	if p.s.due(time.Second / 10) {
+		p.draw()
+	}
+}
+
+// Finish draws final state and moves to the next line.
+func (p *TTYProgress) Finish() {
+	p.draw()
+	fmt.Fprintln(p.w)
+}
+
+func (p *TTYProgress) draw() {
+	bar := ""
+	if done := p.s.done(); done >= 0 {
+		if done > 1 {
+			done = 1
+		}
+		filled := int(done * float64(p.width))
+		bar = fmt.Sprintf("[%s%s] %3.0f%% ", strings.Repeat("=", filled), strings.Repeat(" ", p.width-filled), done*100)
+	}
+	fmt.Fprintf(p.w, "\033[2K\r%s%s", bar, p.s.String())
+}
+
+// LogProgress writes progress to log periodically.
+type LogProgress struct {
+	l        *logrus.Entry
+	interval time.Duration
+	s        progressState
+}
+
+// NewLogProgress creates progress writing to l every interval.
+func NewLogProgress(l *logrus.Entry, interval time.Duration) *LogProgress {
+	return &LogProgress{l: l, interval: interval}
+}
+
+// Start resets the progress.
+func (p *LogProgress) Start(totalRows, totalBytes int64) {
+	p.s.begin(totalRows, totalBytes)
+	p.s.last = p.s.start
+}
+
+// Update writes log line once in interval.
+func (p *LogProgress) Update(rows, bytes int64) {
+	p.s.rows, p.s.bytes = rows, bytes
+	if p.s.due(p.interval) {
+		p.l.Infof("Processing: %s", p.s.String())
+	}
+}
+
+// Finish writes final state.
+func (p *LogProgress) Finish() {
+	p.l.Infof("Processed: %s", p.s.String())
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestProgressState(t *testing.T) {
+	tests := []struct {
+		totalRows, totalBytes, rows, bytes int64
+		done                               float64
+		eta                                time.Duration
+	}{
+		{0, 0, 10, 0, -1, -1},
+		{40, 0, 10, 0, 0.25, 30 * time.Second},
+		{40, 1000, 10, 500, 0.5, 10 * time.Second},
+		// bytes aren't known until read
+		{40, 1000, 20, 0, 0.5, 10 * time.Second},
+		{40, 0, 0, 0, 0, -1},
+	}
+	for _, tt := range tests {
+		var s progressState
+		s.begin(tt.totalRows, tt.totalBytes)
+		s.start = s.start.Add(-10 * time.Second)
+		s.rows, s.bytes = tt.rows, tt.bytes
+		if got := s.done(); got != tt.done {
+			t.Errorf("done() of %+v = %g, want %g", tt, got, tt.done)
+		}
+		if got := s.eta(); (tt.eta < 0) != (got < 0) || (got-tt.eta).Round(time.Second) != 0 {
+			t.Errorf("eta() of %+v = %s, want %s", tt, got, tt.eta)
+		}
+		if got := s.rate(); math.Abs(got-float64(tt.rows)/10) > 0.1 {
+			t.Errorf("rate() of %+v = %g, want %g", tt, got, float64(tt.rows)/10)
+		}
+	}
+
+	var s progressState
+	s.begin(400, 4<<20)
+	s.start = s.start.Add(-10 * time.Second)
+	s.rows, s.bytes = 100, 1<<20
+	if got, want := s.String(), "100 rows, 1.0 MB, 10 rows/s, ETA 30s"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	s.totalRows, s.totalBytes, s.bytes = 0, 0, 0
+	if got, want := s.String(), "100 rows, 10 rows/s"; got != want {
+		t.Errorf("String() of unknown totals = %q, want %q", got, want)
+	}
+}
+
+func TestTTYProgress(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := NewTTYProgress(buf)
+	p.width = 10
+	p.Start(40, 0)
+	p.Update(10, 0)
+	p.Update(20, 0)
+	if n := strings.Count(buf.String(), "\r"); n != 1 {
+		t.Errorf("drawn %d times in a row, want once: %q", n, buf.String())
+	}
+	if !strings.HasPrefix(buf.String(), "\033[2K\r[==        ]  25% 10 rows, ") {
+		t.Errorf("drawn %q", buf.String())
+	}
+
+	// the next update is drawn after interval
+	p.s.last = p.s.last.Add(-time.Second)
+	buf.Reset()
+	p.Update(30, 0)
+	if !strings.HasPrefix(buf.String(), "\033[2K\r[=======   ]  75% 30 rows, ") {
+		t.Errorf("drawn %q after interval", buf.String())
+	}
+
+	// more rows than expected are drawn as a full bar
+	buf.Reset()
+	p.Update(50, 0)
+	p.Finish()
+	if out := buf.String(); !strings.HasPrefix(out, "\033[2K\r[==========] 100% 50 rows, ") || !strings.HasSuffix(out, "\n") {
+		t.Errorf("finished with %q", out)
+	}
+
+	buf.Reset()
+	p.Start(0, 0)
+	p.Update(5, 0)
+	if out := buf.String(); !strings.HasPrefix(out, "\033[2K\r5 rows, ") || strings.Contains(out, "ETA") {
+		t.Errorf("drawn %q without totals", out)
+	}
+}
+
+func TestLogProgress(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.Out = buf
+	logger.Formatter = &logrus.TextFormatter{DisableTimestamp: true}
+	p := NewLogProgress(logrus.NewEntry(logger), time.Minute)
+	p.Start(10, 0)
+	p.Update(1, 0)
+	if buf.Len() != 0 {
+		t.Errorf("logged %q before interval", buf.String())
+	}
+	p.s.last = p.s.last.Add(-time.Minute)
+	p.Update(5, 0)
+	p.Update(6, 0)
+	p.Finish()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "Processing: 5 rows") || !strings.Contains(lines[1], "Processed: 6 rows") {
+		t.Errorf("logged %q", lines)
+	}
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnknownDate reports date has unknown format.
+var ErrUnknownDate = errors.New("unknown date format")
+
+// Granularity is the smallest unit of time set in a parsed date.
+type Granularity int
+
+// Enums for Granularity
+const (
+	GranularitySecond Granularity = iota
+	GranularityMinute
+	GranularityDay
+	GranularityMonth
+	GranularityYear
+)
+
+// End returns the last moment of the period of granularity starting at t,
+// e.g. the end of the day for GranularityDay.
+func (g Granularity) End(t time.Time) time.Time {
+	switch g {
+	case GranularityYear:
+		t = t.AddDate(1, 0, 0)
+	case GranularityMonth:
+		t = t.AddDate(0, 1, 0)
+	case GranularityDay:
+		t = t.AddDate(0, 0, 1)
+	case GranularityMinute:
+		t = t.Add(time.Minute)
+	default:
+		t = t.Add(time.Second)
+	}
+	return t.Add(-time.Nanosecond)
+}
+
+// dateLayouts are formats of dates met in dumps of bash.im.
+var dateLayouts = []struct {
+	layout      string
+	granularity Granularity
+}{
+	{time.RFC3339, GranularitySecond},
+	{"2006-01-02T15:04:05", GranularitySecond},
+	{"2006-01-02 15:04:05", GranularitySecond},
+	{"2006-01-02 15:04", GranularityMinute},
+	{"2006-01-02", GranularityDay},
+	{"02.01.2006 15:04:05", GranularitySecond},
+	{"02.01.2006 15:04", GranularityMinute},
+	{"02.01.2006", GranularityDay},
+	{"2006/01/02 15:04", GranularityMinute},
+	{"2006/01/02", GranularityDay},
+	{"01/02/2006", GranularityDay},
+	{"2006-01", GranularityMonth},
+	{"2006", GranularityYear},
+}
+
+// ParseDate parses date in one of known formats or unix timestamp.
+// It returns the smallest unit set in the date as well.
+func ParseDate(s string) (time.Time, Granularity, error) {
+	s = strings.TrimSpace(s)
+	for _, dl := range dateLayouts {
+		if t, err := time.Parse(dl.layout, s); err == nil {
+			return t, dl.granularity, nil
+		}
+	}
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil && ts > 9999 {
+		return time.Unix(ts, 0).UTC(), GranularitySecond, nil
+	}
+	return time.Time{}, GranularitySecond, ErrUnknownDate
+}
+
+// parseRawDate parses date given as JSON string or unix timestamp.
+func parseRawDate(raw json.RawMessage) (time.Time, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return time.Time{}, ErrUnknownDate
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		s = string(raw)
+	}
+	t, _, err := ParseDate(s)
+	return t, err
+}
+
+// parseNumber parses number given as JSON number or string like "#123".
+func parseNumber(raw json.RawMessage) (int64, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		s = string(raw)
+	}
+	s = strings.TrimLeft(strings.TrimSpace(s), "#+")
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return int64(f), err
+}
+
+// QuoteFilter selects quotes by date and number ranges.
+// Zero values of bounds mean no limit.
+type QuoteFilter struct {
+	From      time.Time
+	To        time.Time
+	MinNumber int64
+	MaxNumber int64
+}
+
+// Match reports whether quote passes the filter. Quotes without
+// date or number don't pass filters which limit them.
+func (f *QuoteFilter) Match(q *BashStruct) bool {
+	if !f.From.IsZero() || !f.To.IsZero() {
+		if q.Date.IsZero() {
+			return false
+		}
+		if !f.From.IsZero() && q.Date.Before(f.From) {
+			return false
+		}
+		if !f.To.IsZero() && q.Date.After(f.To) {
+			return false
+		}
+	}
+	if f.MinNumber > 0 && q.Number < f.MinNumber {
+		return false
+	}
+	if f.MaxNumber > 0 && (q.Number == 0 || q.Number > f.MaxNumber) {
+		return false
+	}
+	return true
+}
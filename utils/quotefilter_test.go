@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in          string
+		want        time.Time
+		granularity Granularity
+	}{
+		{"2005-06-07T08:09:10Z", time.Date(2005, 6, 7, 8, 9, 10, 0, time.UTC), GranularitySecond},
+		{"2005-06-07 08:09:10", time.Date(2005, 6, 7, 8, 9, 10, 0, time.UTC), GranularitySecond},
+		{"2005-06-07 08:09", time.Date(2005, 6, 7, 8, 9, 0, 0, time.UTC), GranularityMinute},
+		{" 2005-06-07 ", time.Date(2005, 6, 7, 0, 0, 0, 0, time.UTC), GranularityDay},
+		{"07.06.2005 08:09", time.Date(2005, 6, 7, 8, 9, 0, 0, time.UTC), GranularityMinute},
+		{"07.06.2005", time.Date(2005, 6, 7, 0, 0, 0, 0, time.UTC), GranularityDay},
+		{"2005/06/07", time.Date(2005, 6, 7, 0, 0, 0, 0, time.UTC), GranularityDay},
+		{"06/07/2005", time.Date(2005, 6, 7, 0, 0, 0, 0, time.UTC), GranularityDay},
+		{"2005-06", time.Date(2005, 6, 1, 0, 0, 0, 0, time.UTC), GranularityMonth},
+		{"2005", time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC), GranularityYear},
+		{"1118131750", time.Unix(1118131750, 0).UTC(), GranularitySecond},
+	}
+	for _, tt := range tests {
+		got, g, err := ParseDate(tt.in)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) || g != tt.granularity {
+			t.Errorf("ParseDate(%q) = %v, %d, want %v, %d", tt.in, got, g, tt.want, tt.granularity)
+		}
+	}
+	for _, in := range []string{"", "вчера", "2005-13", "123"} {
+		if _, _, err := ParseDate(in); err != ErrUnknownDate {
+			t.Errorf("ParseDate(%q) error = %v, want ErrUnknownDate", in, err)
+		}
+	}
+}
+
+func TestGranularityEnd(t *testing.T) {
+	tests := []struct {
+		g     Granularity
+		start time.Time
+		want  time.Time
+	}{
+		{GranularityYear, time.Date(2004, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2004, 12, 31, 23, 59, 59, 999999999, time.UTC)},
+		{GranularityMonth, time.Date(2004, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2004, 2, 29, 23, 59, 59, 999999999, time.UTC)},
+		{GranularityDay, time.Date(2004, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2004, 2, 1, 23, 59, 59, 999999999, time.UTC)},
+		{GranularityMinute, time.Date(2004, 2, 1, 10, 20, 0, 0, time.UTC), time.Date(2004, 2, 1, 10, 20, 59, 999999999, time.UTC)},
+		{GranularitySecond, time.Date(2004, 2, 1, 10, 20, 30, 0, time.UTC), time.Date(2004, 2, 1, 10, 20, 30, 999999999, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := tt.g.End(tt.start); !got.Equal(tt.want) {
+			t.Errorf("End(%d) of %v = %v, want %v", tt.g, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteFilterMatch(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _, err := ParseDate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	f := QuoteFilter{From: date("2005-01-01"), To: date("2005-12-31 23:59"), MinNumber: 100, MaxNumber: 200}
+	tests := []struct {
+		name string
+		q    BashStruct
+		want bool
+	}{
+		{"inside", BashStruct{Date: date("2005-06-07"), Number: 150}, true},
+		{"bounds", BashStruct{Date: date("2005-01-01"), Number: 100}, true},
+		{"too early", BashStruct{Date: date("2004-12-31"), Number: 150}, false},
+		{"too late", BashStruct{Date: date("2006-01-01"), Number: 150}, false},
+		{"no date", BashStruct{Number: 150}, false},
+		{"small number", BashStruct{Date: date("2005-06-07"), Number: 99}, false},
+		{"big number", BashStruct{Date: date("2005-06-07"), Number: 201}, false},
+		{"no number", BashStruct{Date: date("2005-06-07")}, false},
+	}
+	for _, tt := range tests {
+		if got := f.Match(&tt.q); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	var empty QuoteFilter
+	if !empty.Match(&BashStruct{}) {
+		t.Error("empty filter must match everything")
+	}
+}
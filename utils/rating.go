@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RatingWeight returns weight of quote in training based on its rating.
+// Quotes with zero weight must be skipped.
+type RatingWeight func(q *BashStruct) float64
+
+// defaultLinearScale is rating which gives weight 1 for linear weighting.
+const defaultLinearScale = 1000
+
+// ParseRatingWeight creates weighting function by its spec:
+//
+//	none         every quote has weight 1
+//	linear[:N]   weight is rating divided by N (1000 by default),
+//	             quotes with non-positive rating are skipped
+//	log          weight is 1 + ln(1 + rating)
+//	threshold:N  quotes with rating less than N are skipped
+//
+// Quotes without rating have weight 1 except for threshold, which skips them.
+func ParseRatingWeight(spec string) (RatingWeight, error) {
+	name, arg := spec, ""
+	if i := strings.IndexByte(spec, ':'); i >= 0 {
+		name, arg = spec[:i], spec[i+1:]
+	}
+	var n float64
+	if arg != "" {
+		var err error
+		if n, err = strconv.ParseFloat(arg, 64); err != nil {
+			return nil, fmt.Errorf("rating weight %q: %w", spec, err)
+		}
+	}
+
+	switch name {
+	case "", "none":
+		return func(*BashStruct) float64 { return 1 }, nil
+	case "linear":
+		if arg == "" {
+			n = defaultLinearScale
+		} else if n <= 0 {
+			return nil, fmt.Errorf("rating weight %q: scale must be positive", spec)
+		}
+		return func(q *BashStruct) float64 {
+			if q.Rating == nil {
+				return 1
+			}
+			return math.Max(float64(*q.Rating), 0) / n
+		}, nil
+	case "log":
+		return func(q *BashStruct) float64 {
+			if q.Rating == nil {
+				return 1
+			}
+			return 1 + math.Log1p(math.Max(float64(*q.Rating), 0))
+		}, nil
+	case "threshold":
+		if arg == "" {
+			return nil, fmt.Errorf("rating weight %q: threshold is required", spec)
+		}
+		return func(q *BashStruct) float64 {
+			if q.Rating == nil || float64(*q.Rating) < n {
+				return 0
+			}
+			return 1
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown rating weight %q", spec)
+}
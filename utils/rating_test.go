@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseRatingWeight(t *testing.T) {
+	rating := func(r int64) *BashStruct { return &BashStruct{Rating: &r} }
+	unrated := &BashStruct{}
+	tests := []struct {
+		spec string
+		q    *BashStruct
+		want float64
+	}{
+		{"", rating(500), 1},
+		{"none", unrated, 1},
+		{"linear", rating(500), 0.5},
+		{"linear", rating(-20), 0},
+		{"linear", unrated, 1},
+		{"linear:100", rating(250), 2.5},
+		{"log", rating(0), 1},
+		{"log", rating(100), 1 + math.Log(101)},
+		{"log", rating(-100), 1},
+		{"log", unrated, 1},
+		{"threshold:100", rating(100), 1},
+		{"threshold:100", rating(99), 0},
+		{"threshold:100", unrated, 0},
+	}
+	for _, tt := range tests {
+		w, err := ParseRatingWeight(tt.spec)
+		if err != nil {
+			t.Errorf("ParseRatingWeight(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := w(tt.q); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%q weight = %g, want %g", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseRatingWeightErrors(t *testing.T) {
+	for _, spec := range []string{"linear:abc", "linear:0", "linear:-5", "threshold", "threshold:x", "square"} {
+		if _, err := ParseRatingWeight(spec); err == nil {
+			t.Errorf("ParseRatingWeight(%q) must fail", spec)
+		}
+	}
+}